@@ -12,6 +12,8 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	"radaroficial.app/internal/api"
+	"radaroficial.app/internal/jobs"
+	"radaroficial.app/internal/vectorstore"
 )
 
 var pool *pgxpool.Pool
@@ -47,13 +49,28 @@ func main() {
 	defer stop() // stop receiving signals
 	defer pool.Close()
 
+	// Create or migrate the vector store schema before anything is indexed or searched
+	vectorStore, err := vectorstore.NewVectorStoreFromEnv(pool)
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize vector store: %v", err)
+	}
+	if err := vectorStore.EnsureSchema(ctx); err != nil {
+		log.Fatalf("❌ Failed to ensure vector store schema: %v", err)
+	}
+
+	// Jobs scheduled through the API are inserted for the worker, which runs them
+	riverClient, err := jobs.NewInsertOnlyClient(pool)
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize River client: %v", err)
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
 	// Initialize and start the server
-	server := api.NewServer(pool)
+	server := api.NewServer(pool, vectorStore, riverClient)
 	server.RegisterHandlers()
 
 	// Start the server in a goroutine
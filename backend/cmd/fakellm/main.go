@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/joho/godotenv"
+	"radaroficial.app/internal/llm"
+)
+
+// fakellm serves scripted model replies and accepts WhatsApp messages, to run the api
+// offline with LLM_BASE_URL=http://localhost:8089/v1 and
+// WHATSAPP_API_URL=http://localhost:8089
+func main() {
+	_ = godotenv.Load()
+
+	script := llm.Script{}
+	if path := os.Getenv("FAKE_LLM_SCRIPT"); path != "" {
+		var err error
+		script, err = llm.LoadScript(path)
+		if err != nil {
+			log.Fatalf("❌ Failed to load script: %v", err)
+		}
+	}
+
+	server, err := llm.NewFakeServer(script)
+	if err != nil {
+		log.Fatalf("❌ Failed to create fake server: %v", err)
+	}
+
+	port := os.Getenv("FAKE_LLM_PORT")
+	if port == "" {
+		port = "8089"
+	}
+
+	log.Printf("🤖 Fake model server running on port %s with %d scripted response(s)", port, len(script.Responses))
+	log.Fatal(http.ListenAndServe(":"+port, server))
+}
@@ -0,0 +1,15 @@
+CREATE TABLE backfills (
+    id SERIAL PRIMARY KEY,
+    institution_id INTEGER NOT NULL REFERENCES institutions(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    next_date DATE NOT NULL,
+    enqueued_days INTEGER NOT NULL DEFAULT 0,
+    completed_at TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT backfills_date_range_check CHECK (start_date <= end_date),
+    CONSTRAINT backfills_institution_range_key UNIQUE (institution_id, start_date, end_date)
+);
+
+COMMENT ON TABLE backfills IS 'Tracks the progress of historical backfills so restarted jobs resume where they stopped';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_diarios_institution_edition_number;
+
+ALTER TABLE diarios
+DROP COLUMN IF EXISTS edition_number;
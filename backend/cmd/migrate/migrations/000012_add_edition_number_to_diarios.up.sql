@@ -0,0 +1,9 @@
+ALTER TABLE diarios
+ADD COLUMN edition_number INTEGER;
+
+-- Editions of the Diário dos Municípios are described as "Edição 5302 (16/04/2025)"
+UPDATE diarios
+SET edition_number = substring(description FROM 'Edição (\d+)')::INTEGER
+WHERE description ~ 'Edição \d+';
+
+CREATE INDEX IF NOT EXISTS idx_diarios_institution_edition_number ON diarios(institution_id, edition_number);
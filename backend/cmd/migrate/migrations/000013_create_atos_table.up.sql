@@ -0,0 +1,22 @@
+CREATE TABLE atos (
+    id SERIAL PRIMARY KEY,
+    diario_id INTEGER NOT NULL REFERENCES diarios(id) ON DELETE CASCADE,
+    seq INTEGER NOT NULL,
+    orgao TEXT,
+    act_type TEXT NOT NULL,
+    number TEXT,
+    year INTEGER,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    page_start INTEGER NOT NULL,
+    page_end INTEGER NOT NULL,
+    ocr BOOLEAN NOT NULL DEFAULT FALSE,
+    ocr_confidence REAL,
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT atos_diario_seq_key UNIQUE (diario_id, seq),
+    CONSTRAINT atos_page_span_check CHECK (page_start <= page_end)
+);
+
+CREATE INDEX IF NOT EXISTS idx_atos_act_type_year ON atos(act_type, year);
+
+COMMENT ON TABLE atos IS 'Individual acts (portarias, decretos, extratos...) segmented from each diario';
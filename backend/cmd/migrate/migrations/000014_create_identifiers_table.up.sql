@@ -0,0 +1,18 @@
+CREATE TABLE identifiers (
+    id SERIAL PRIMARY KEY,
+    ato_id INTEGER NOT NULL REFERENCES atos(id) ON DELETE CASCADE,
+    diario_id INTEGER NOT NULL REFERENCES diarios(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    value TEXT NOT NULL,
+    normalized TEXT NOT NULL,
+    amount NUMERIC(15, 2),
+    created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT identifiers_ato_kind_normalized_key UNIQUE (ato_id, kind, normalized),
+    CONSTRAINT identifiers_kind_check CHECK (kind IN ('processo', 'cnpj', 'cpf', 'contrato', 'pregao', 'valor'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_identifiers_kind_normalized ON identifiers(kind, normalized);
+CREATE INDEX IF NOT EXISTS idx_identifiers_normalized ON identifiers(normalized);
+CREATE INDEX IF NOT EXISTS idx_identifiers_diario_id ON identifiers(diario_id);
+
+COMMENT ON TABLE identifiers IS 'Process numbers, CNPJs, masked CPFs, contract and pregão numbers and monetary values cited by each ato';
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_diarios_institution_sha256;
+
+ALTER TABLE diarios
+DROP COLUMN IF EXISTS object_key,
+DROP COLUMN IF EXISTS size_bytes,
+DROP COLUMN IF EXISTS sha256;
@@ -0,0 +1,12 @@
+ALTER TABLE diarios
+ADD COLUMN sha256 TEXT,
+ADD COLUMN size_bytes BIGINT,
+ADD COLUMN object_key TEXT;
+
+-- Existing objects were stored under the path of their public URL
+UPDATE diarios
+SET object_key = regexp_replace(source_url, '^https?://[^/]+/', '')
+WHERE object_key IS NULL;
+
+-- The same PDF is stored once per institution, whatever its description
+CREATE UNIQUE INDEX IF NOT EXISTS idx_diarios_institution_sha256 ON diarios(institution_id, sha256) WHERE sha256 IS NOT NULL;
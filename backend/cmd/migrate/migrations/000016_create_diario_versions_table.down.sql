@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS diario_versions;
+
+ALTER TABLE diarios
+DROP COLUMN IF EXISTS version;
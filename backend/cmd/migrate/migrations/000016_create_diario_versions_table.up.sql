@@ -0,0 +1,16 @@
+ALTER TABLE diarios
+ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- Previous versions of diarios whose PDF was replaced at the source, e.g. after an errata
+CREATE TABLE diario_versions (
+    id SERIAL PRIMARY KEY,
+    diario_id INTEGER NOT NULL REFERENCES diarios(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    source_url TEXT,
+    last_modified_at TIMESTAMP WITHOUT TIME ZONE,
+    sha256 TEXT,
+    size_bytes BIGINT,
+    object_key TEXT,
+    superseded_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT diario_versions_diario_version_key UNIQUE (diario_id, version)
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_diarios_status;
+
+ALTER TABLE diarios
+DROP COLUMN IF EXISTS download_url,
+DROP COLUMN IF EXISTS status;
@@ -0,0 +1,9 @@
+-- Existing diarios went through the whole ingestion in a single job
+ALTER TABLE diarios
+ADD COLUMN status TEXT NOT NULL DEFAULT 'ready',
+ADD COLUMN download_url TEXT;
+
+ALTER TABLE diarios
+ALTER COLUMN status SET DEFAULT 'discovered';
+
+CREATE INDEX IF NOT EXISTS idx_diarios_status ON diarios(status);
@@ -0,0 +1,23 @@
+CREATE INDEX IF NOT EXISTS idx_diarios_status ON diarios(status);
+DROP INDEX IF EXISTS idx_diarios_status_changed_at;
+
+ALTER TABLE diarios
+DROP CONSTRAINT IF EXISTS diarios_status_check;
+
+ALTER TABLE diarios
+ADD COLUMN indexing_submitted_at TIMESTAMP WITHOUT TIME ZONE;
+
+UPDATE diarios SET indexing_submitted_at = indexed_at;
+
+ALTER TABLE diarios
+DROP COLUMN IF EXISTS last_error_at,
+DROP COLUMN IF EXISTS last_error,
+DROP COLUMN IF EXISTS superseded_at,
+DROP COLUMN IF EXISTS failed_at,
+DROP COLUMN IF EXISTS indexed_at,
+DROP COLUMN IF EXISTS extracted_at,
+DROP COLUMN IF EXISTS downloaded_at,
+DROP COLUMN IF EXISTS status_changed_at;
+
+UPDATE diarios SET status = 'ready' WHERE status IN ('indexed', 'failed');
+UPDATE diarios SET status = 'duplicate' WHERE status = 'superseded';
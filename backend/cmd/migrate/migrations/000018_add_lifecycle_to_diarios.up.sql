@@ -0,0 +1,29 @@
+-- Statuses of the lifecycle state machine, see internal/diarios/lifecycle.go
+UPDATE diarios SET status = 'indexed' WHERE status = 'ready';
+UPDATE diarios SET status = 'superseded' WHERE status = 'duplicate';
+
+ALTER TABLE diarios
+ADD COLUMN status_changed_at TIMESTAMP WITHOUT TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+ADD COLUMN downloaded_at TIMESTAMP WITHOUT TIME ZONE,
+ADD COLUMN extracted_at TIMESTAMP WITHOUT TIME ZONE,
+ADD COLUMN indexed_at TIMESTAMP WITHOUT TIME ZONE,
+ADD COLUMN failed_at TIMESTAMP WITHOUT TIME ZONE,
+ADD COLUMN superseded_at TIMESTAMP WITHOUT TIME ZONE,
+ADD COLUMN last_error TEXT,
+ADD COLUMN last_error_at TIMESTAMP WITHOUT TIME ZONE;
+
+-- indexing_submitted_at tracked the DigitalOcean knowledge base, replaced by the statuses
+UPDATE diarios
+SET
+    status_changed_at = COALESCE(indexing_submitted_at, updated_at),
+    indexed_at = CASE WHEN status = 'indexed' THEN COALESCE(indexing_submitted_at, updated_at) END;
+
+ALTER TABLE diarios
+DROP COLUMN IF EXISTS indexing_submitted_at;
+
+ALTER TABLE diarios
+ADD CONSTRAINT diarios_status_check
+CHECK (status IN ('discovered', 'downloaded', 'extracted', 'indexed', 'failed', 'superseded'));
+
+CREATE INDEX IF NOT EXISTS idx_diarios_status_changed_at ON diarios(status, status_changed_at);
+DROP INDEX IF EXISTS idx_diarios_status;
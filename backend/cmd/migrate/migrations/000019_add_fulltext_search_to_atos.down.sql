@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_atos_tsv;
+
+ALTER TABLE atos DROP COLUMN IF EXISTS tsv;
+
+DROP TEXT SEARCH CONFIGURATION IF EXISTS public.portuguese_unaccent;
@@ -0,0 +1,19 @@
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- Portuguese stemming over unaccented words, so "nomeacao" finds "nomeação"
+CREATE TEXT SEARCH CONFIGURATION public.portuguese_unaccent (COPY = pg_catalog.portuguese);
+
+ALTER TEXT SEARCH CONFIGURATION public.portuguese_unaccent
+    ALTER MAPPING FOR hword, hword_part, word
+    WITH unaccent, portuguese_stem;
+
+-- Titles rank above the body of the ato
+ALTER TABLE atos
+    ADD COLUMN tsv tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('public.portuguese_unaccent', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('public.portuguese_unaccent', COALESCE(content, '')), 'B')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_atos_tsv ON atos USING GIN (tsv);
+
+COMMENT ON COLUMN atos.tsv IS 'Title (weight A) and content (weight B) for Portuguese full-text search, unaccented';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_diarios_institution_published_at;
+
+ALTER TABLE diarios
+DROP COLUMN IF EXISTS page_count;
@@ -0,0 +1,10 @@
+ALTER TABLE diarios
+ADD COLUMN page_count INTEGER;
+
+-- Diarios extracted before the column existed end at the last page of their atos
+UPDATE diarios d
+SET page_count = a.last_page
+FROM (SELECT diario_id, MAX(page_end) AS last_page FROM atos GROUP BY diario_id) a
+WHERE a.diario_id = d.id;
+
+CREATE INDEX IF NOT EXISTS idx_diarios_institution_published_at ON diarios(institution_id, published_at DESC);
@@ -0,0 +1,8 @@
+CREATE TABLE backfill_days (
+    backfill_id INTEGER NOT NULL REFERENCES backfills(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    fetched_at TIMESTAMP WITHOUT TIME ZONE,
+    PRIMARY KEY (backfill_id, day)
+);
+
+COMMENT ON TABLE backfill_days IS 'Days enqueued by a backfill, fetched once their fetch job succeeded';
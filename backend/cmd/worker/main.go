@@ -12,7 +12,9 @@ import (
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
+	"radaroficial.app/internal/diarios"
 	"radaroficial.app/internal/jobs"
+	"radaroficial.app/internal/vectorstore"
 )
 
 var pool *pgxpool.Pool
@@ -75,6 +77,15 @@ func main() {
 	}
 	log.Printf("✅ Connected to database")
 
+	// Create or migrate the vector store schema before anything is indexed or searched
+	vectorStore, err := vectorstore.NewVectorStoreFromEnv(pool)
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize vector store: %v", err)
+	}
+	if err := vectorStore.EnsureSchema(ctx); err != nil {
+		log.Fatalf("❌ Failed to ensure vector store schema: %v", err)
+	}
+
 	// Initialize and start River Queue client
 	riverClient, err := jobs.NewRiverClient(ctx, pool)
 	if err != nil {
@@ -82,9 +93,11 @@ func main() {
 	}
 
 	// Log active periodic jobs
-	log.Printf("🔄 Worker configured with the following periodic jobs running every hour:")
-	log.Printf("  • Diário dos Municípios do Piauí")
-	log.Printf("  • Diários Oficiais do Governo do Piauí")
+	log.Printf("🔄 Worker configured with the following periodic jobs:")
+	for _, src := range diarios.Sources() {
+		meta := src.Metadata()
+		log.Printf("  • %s (%s) every %s", meta.Name, meta.Slug, meta.PollInterval)
+	}
 
 	// Wait for termination signal
 	log.Printf("🔄 Worker is now running. Press Ctrl+C to exit...")
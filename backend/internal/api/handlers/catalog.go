@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"radaroficial.app/internal/diarios"
+	"radaroficial.app/internal/institutions"
+	"radaroficial.app/internal/model"
+)
+
+var institutionTypes = []string{"federal", "state", "municipal"}
+
+type catalogDiario struct {
+	ID              int        `json:"id"`
+	InstitutionID   int        `json:"institutionId"`
+	Description     *string    `json:"description"`
+	EditionNumber   *int       `json:"editionNumber,omitempty"`
+	PublishedAt     *time.Time `json:"publishedAt"`
+	PageCount       *int       `json:"pageCount"`
+	SizeBytes       *int64     `json:"sizeBytes,omitempty"`
+	SHA256          *string    `json:"sha256,omitempty"`
+	Version         int        `json:"version"`
+	Status          string     `json:"status"`
+	StatusChangedAt *time.Time `json:"statusChangedAt"`
+	IndexedAt       *time.Time `json:"indexedAt,omitempty"`
+	LastError       *string    `json:"lastError,omitempty"`
+	DownloadURL     string     `json:"downloadUrl,omitempty"` // Stored PDF
+	SourceURL       *string    `json:"sourceUrl,omitempty"`   // PDF at the source
+}
+
+func catalogDiarioOf(d *model.Diario) catalogDiario {
+	return catalogDiario{
+		ID:              d.ID,
+		InstitutionID:   d.InstitutionID,
+		Description:     d.Description,
+		EditionNumber:   d.EditionNumber,
+		PublishedAt:     d.PublishedAt,
+		PageCount:       d.PageCount,
+		SizeBytes:       d.SizeBytes,
+		SHA256:          d.SHA256,
+		Version:         d.Version,
+		Status:          d.Status,
+		StatusChangedAt: d.StatusChangedAt,
+		IndexedAt:       d.IndexedAt,
+		LastError:       d.LastError,
+		DownloadURL:     d.SourceURL,
+		SourceURL:       d.DownloadURL,
+	}
+}
+
+type InstitutionsHandler struct{ DB *pgxpool.Pool }
+
+func NewInstitutionsHandler(db *pgxpool.Pool) *InstitutionsHandler {
+	return &InstitutionsHandler{DB: db}
+}
+
+// ServeHTTP lists the institutions, filtered by type, state, city and active (true or
+// false)
+func (h *InstitutionsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != "GET" {
+		http.NotFound(w, r)
+		return
+	}
+
+	queryValues := r.URL.Query()
+
+	filter := institutions.ListFilter{
+		Type:  queryValues.Get("type"),
+		State: strings.ToUpper(strings.TrimSpace(queryValues.Get("state"))),
+		City:  strings.TrimSpace(queryValues.Get("city")),
+	}
+
+	if filter.Type != "" && !slices.Contains(institutionTypes, filter.Type) {
+		http.Error(w, "Invalid type, expected federal, state or municipal", http.StatusBadRequest)
+		return
+	}
+
+	if queryValues.Has("active") {
+		active, err := strconv.ParseBool(queryValues.Get("active"))
+		if err != nil {
+			http.Error(w, "Invalid active, expected true or false", http.StatusBadRequest)
+			return
+		}
+		filter.Active = &active
+	}
+
+	srv := institutions.NewInstitutionService(h.DB)
+	list, err := srv.List(r.Context(), filter)
+	if err != nil {
+		log.Printf("❌ Failed to list institutions: %v", err)
+		http.Error(w, "Failed to list institutions.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"institutions": list,
+	})
+}
+
+type InstitutionDiariosHandler struct{ DB *pgxpool.Pool }
+
+func NewInstitutionDiariosHandler(db *pgxpool.Pool) *InstitutionDiariosHandler {
+	return &InstitutionDiariosHandler{DB: db}
+}
+
+// ServeHTTP lists the diarios of the institution with the slug in the path, newest
+// first, published between from and to (YYYY-MM-DD, both inclusive) and paginated by
+// limit and offset
+func (h *InstitutionDiariosHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != "GET" {
+		http.NotFound(w, r)
+		return
+	}
+
+	queryValues := r.URL.Query()
+
+	from, to, err := dateRange(queryValues.Get("from"), queryValues.Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := pageParams(queryValues.Get("limit"), queryValues.Get("offset"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	institution, err := institutions.NewInstitutionService(h.DB).GetBySlug(r.Context(), r.PathValue("slug"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to get institution: %v", err)
+		http.Error(w, "Failed to list diarios.", http.StatusInternalServerError)
+		return
+	}
+
+	srv := diarios.NewInstitutionService(h.DB)
+	list, total, err := srv.ListByInstitution(r.Context(), institution.ID, from, to, limit, offset)
+	if err != nil {
+		log.Printf("❌ Failed to list diarios of %s: %v", institution.Slug, err)
+		http.Error(w, "Failed to list diarios.", http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]catalogDiario, 0, len(list))
+	for _, d := range list {
+		items = append(items, catalogDiarioOf(d))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"institution": institution,
+		"diarios":     items,
+		"total":       total,
+		"limit":       limit,
+		"offset":      offset,
+	})
+}
+
+type DiarioHandler struct{ DB *pgxpool.Pool }
+
+func NewDiarioHandler(db *pgxpool.Pool) *DiarioHandler {
+	return &DiarioHandler{DB: db}
+}
+
+// ServeHTTP returns the diario with the ID in the path and its institution
+func (h *DiarioHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != "GET" {
+		http.NotFound(w, r)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	diario, err := diarios.NewInstitutionService(h.DB).GetByID(r.Context(), id)
+	if err != nil {
+		log.Printf("❌ Failed to get diario %d: %v", id, err)
+		http.Error(w, "Failed to get diario.", http.StatusInternalServerError)
+		return
+	}
+	if diario == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	institution, err := institutions.NewInstitutionService(h.DB).GetByID(r.Context(), diario.InstitutionID)
+	if err != nil {
+		log.Printf("❌ Failed to get institution of diario %d: %v", id, err)
+		http.Error(w, "Failed to get diario.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"diario":      catalogDiarioOf(diario),
+		"institution": institution,
+	})
+}
+
+// dateRange parses an inclusive range of dates (YYYY-MM-DD) into [from, to)
+func dateRange(fromParam, toParam string) (from, to *time.Time, err error) {
+	if fromParam != "" {
+		t, err := time.Parse(time.DateOnly, fromParam)
+		if err != nil {
+			return nil, nil, errors.New("Invalid from, expected YYYY-MM-DD")
+		}
+		from = &t
+	}
+
+	if toParam != "" {
+		t, err := time.Parse(time.DateOnly, toParam)
+		if err != nil {
+			return nil, nil, errors.New("Invalid to, expected YYYY-MM-DD")
+		}
+		t = t.AddDate(0, 0, 1)
+		to = &t
+	}
+
+	return from, to, nil
+}
@@ -1,33 +1,30 @@
 package handlers
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"radaroficial.app/internal/chat"
-	"radaroficial.app/internal/diarios"
+	"radaroficial.app/internal/llm"
+	"radaroficial.app/internal/search"
 )
 
-// ChatHandler handles incoming webhook requests from WhatsApp
+// ChatHandler answers the questions of the assistant-ui thread
 type ChatHandler struct {
-	diarioService *diarios.DiarioService
-	chatService   *chat.ChatService
-	db            *pgxpool.Pool
+	chatService *chat.ChatService
+	db          *pgxpool.Pool
 }
 
-func NewChatHandler(db *pgxpool.Pool) *ChatHandler {
-
+func NewChatHandler(db *pgxpool.Pool, semantic search.Retriever) *ChatHandler {
 	return &ChatHandler{
-		diarioService: diarios.NewInstitutionService(db),
-		chatService:   chat.NewChatService(),
-		db:            db,
+		chatService: chat.NewChatService(db, semantic),
+		db:          db,
 	}
 }
 
@@ -41,12 +38,25 @@ func (h *ChatHandler) chatCompletion(w http.ResponseWriter, r *http.Request) {
 
 	// if somehow the state is not present, trigger the select-diario-state tool
 	if !queryValues.Has("state") {
+		if wantsStream(r) {
+			stream := newDataStream(w)
+			stream.toolCall(newToolCallID(), selectDiarioStateTool, "{}")
+			stream.finish(finishToolCalls)
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(selectDiarioStateToolCall())
 		return
 	}
 
-	state := queryValues.Get("state")
+	// the UI restricts the question to a state, and may restrict it to a period,
+	// institutions or municipalities
+	selected, err := search.FilterFromQuery(queryValues)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -61,112 +71,44 @@ func (h *ChatHandler) chatCompletion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	lastMessage := message.Messages[len(message.Messages)-1]
-
-	agentResponse, err := h.sendMessageToAIAgent(state, lastMessage.Content[0].Text)
-
-	if err != nil {
-		log.Printf("❌ Failed to process chat completion: %v", err)
-		http.Error(w, "Failed to process chat completion", http.StatusInternalServerError)
+	if len(message.Messages) == 0 {
+		http.Error(w, "Missing messages", http.StatusBadRequest)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"text": agentResponse,
-	})
-
-}
-
-// sendMessageToAIAgent sends a message to the Digital Ocean AI agent and returns the response
-func (h *ChatHandler) sendMessageToAIAgent(state, message string) (string, error) {
-
-	url, agentAccessKey, err := getAgentSettings(state)
-	if err != nil {
-		return "", err
-	}
-
-	// Construct the request payload
-	type Message struct {
-		Role    string `json:"role"`
-		Content string `json:"content"`
-	}
-
-	payload := struct {
-		Messages              []Message `json:"messages"`
-		Stream                bool      `json:"stream"`
-		IncludeFunctionsInfo  bool      `json:"include_functions_info"`
-		IncludeRetrievalInfo  bool      `json:"include_retrieval_info"`
-		IncludeGuardrailsInfo bool      `json:"include_guardrails_info"`
-	}{
-		Messages: []Message{
-			{
-				Role:    "user",
-				Content: message,
-			},
-		},
-		Stream:                false,
-		IncludeFunctionsInfo:  false,
-		IncludeRetrievalInfo:  false,
-		IncludeGuardrailsInfo: false,
-	}
-
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		return "", err
-	}
+	lastMessage := message.Messages[len(message.Messages)-1]
 
-	// Create the request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return "", err
+	question := lastMessage.text()
+	if lastMessage.Role != "user" || question == "" {
+		http.Error(w, "The last message must be a user message with text", http.StatusBadRequest)
+		return
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+agentAccessKey)
-
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
+	q := chat.Question{
+		Text:    question,
+		Filter:  selected,
+		History: historyOf(message.Messages[:len(message.Messages)-1]),
 	}
-	defer resp.Body.Close()
 
-	// Check for errors
-	if resp.StatusCode >= 400 {
-		responseBody, _ := io.ReadAll(resp.Body)
-		log.Printf("❌ AI agent API error (status %d): %s", resp.StatusCode, string(responseBody))
-		return "", fmt.Errorf("AI agent API error: %d", resp.StatusCode)
+	if wantsStream(r) {
+		h.streamAnswer(w, r, q)
+		return
 	}
 
-	// Parse the response
-	type AIResponse struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-	}
+	answer, err := h.chatService.Answer(r.Context(), q)
 
-	var aiResponse AIResponse
-	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
-	}
-
-	if err := json.Unmarshal(responseBody, &aiResponse); err != nil {
-		return "", err
+		log.Printf("❌ Failed to process chat completion: %v", err)
+		http.Error(w, "Failed to process chat completion", http.StatusInternalServerError)
+		return
 	}
 
-	// Extract the response message
-	if len(aiResponse.Choices) == 0 {
-		return "", fmt.Errorf("no response from AI agent")
-	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"text":      answer.Text,
+		"citations": answer.Citations,
+	})
 
-	// log.Printf("✅ Got response from AI agent: %s", aiResponse.Choices[0].Message.Content)
-	return aiResponse.Choices[0].Message.Content, nil
 }
 
 type MessageSet struct {
@@ -174,18 +116,87 @@ type MessageSet struct {
 }
 
 type Message struct {
-	ID          string        `json:"id"`
-	CreatedAt   string        `json:"createdAt"` // Use time.Time if you want to parse the timestamp
-	Role        string        `json:"role"`
-	Content     []Content     `json:"content"`
-	Attachments []interface{} `json:"attachments,omitempty"` // Or define a struct if needed
-	Metadata    Metadata      `json:"metadata"`
-	Status      *Status       `json:"status,omitempty"` // Present only for assistant messages
+	ID          string       `json:"id"`
+	CreatedAt   string       `json:"createdAt"` // Use time.Time if you want to parse the timestamp
+	Role        string       `json:"role"`
+	Content     []Content    `json:"content"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	Metadata    Metadata     `json:"metadata"`
+	Status      *Status      `json:"status,omitempty"` // Present only for assistant messages
 }
 
+// Content is a part of a message: text, reasoning, image, file or tool-call
 type Content struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Image    string `json:"image,omitempty"`    // URL or data URL of an image part
+	MimeType string `json:"mimeType,omitempty"` // Of a file part
+	ToolName string `json:"toolName,omitempty"` // Of a tool-call part
+	Result   any    `json:"result,omitempty"`   // Of a tool-call part
+}
+
+// Attachment is a file attached to a user message, with its content as parts
+type Attachment struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"` // image, document or file
+	Name        string    `json:"name"`
+	ContentType string    `json:"contentType,omitempty"`
+	Content     []Content `json:"content,omitempty"`
+}
+
+// text returns the text the model should read for a message: its text parts, then the
+// text of its attachments. Images, files and tool calls are only named, the model
+// cannot read them.
+func (m Message) text() string {
+	var parts []string
+
+	for _, c := range m.Content {
+		switch c.Type {
+		case "text":
+			if text := strings.TrimSpace(c.Text); text != "" {
+				parts = append(parts, text)
+			}
+		case "image":
+			parts = append(parts, "[imagem enviada]")
+		case "file":
+			parts = append(parts, "[arquivo enviado: "+c.MimeType+"]")
+		}
+	}
+
+	for _, a := range m.Attachments {
+		var texts []string
+		for _, c := range a.Content {
+			if c.Type == "text" && strings.TrimSpace(c.Text) != "" {
+				texts = append(texts, strings.TrimSpace(c.Text))
+			}
+		}
+
+		if len(texts) == 0 {
+			parts = append(parts, fmt.Sprintf("[anexo %q não lido]", a.Name))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("Anexo %q:\n%s", a.Name, strings.Join(texts, "\n")))
+	}
+
+	return strings.Join(parts, "\n\n")
+}
+
+// historyOf returns the turns of the previous messages of a thread with text, skipping
+// tool calls such as select-diario-state and failed answers
+func historyOf(messages []Message) []chat.Turn {
+	var turns []chat.Turn
+	for _, m := range messages {
+		if m.Role != "user" && m.Role != "assistant" {
+			continue
+		}
+		if m.Status != nil && m.Status.Type == "incomplete" {
+			continue
+		}
+		if text := m.text(); text != "" {
+			turns = append(turns, chat.Turn{Role: m.Role, Text: text})
+		}
+	}
+	return turns
 }
 
 type Metadata struct {
@@ -201,30 +212,44 @@ type Status struct {
 	Error  string `json:"error"`
 }
 
-// Get endpoint and access key from environment variables
-func getAgentSettings(state string) (url, accessKey string, err error) {
-
-	var agentEndpoint string
-
-	switch state {
-	case "PI":
-		agentEndpoint = os.Getenv("DO_AGENT_PIAUI_URL")
-		if agentEndpoint == "" {
-			return "", "", fmt.Errorf("DO_AGENT_PIAUI_URL environment variable not set")
+// streamAnswer writes the answer as a data stream while the model generates it. When the
+// client disconnects, the request context is canceled, which stops the model stream.
+func (h *ChatHandler) streamAnswer(w http.ResponseWriter, r *http.Request, q chat.Question) {
+	stream := newDataStream(w)
+	stream.start(fmt.Sprintf("msg-%d", time.Now().UnixNano()))
+
+	finishReason := finishStop
+	answer, err := h.chatService.AnswerStream(r.Context(), q, func(delta llm.Delta) error {
+		if delta.ToolCall != nil {
+			finishReason = finishToolCalls
+			return stream.toolCall(delta.ToolCall.ID, delta.ToolCall.Name, delta.ToolCall.Arguments)
 		}
+		return stream.text(delta.Content)
+	})
 
-		accessKey = os.Getenv("DO_AGENT_PIAUI_ACCESS_KEY")
-		if accessKey == "" {
-			return "", "", fmt.Errorf("DO_AGENT_PIAUI_ACCESS_KEY environment variable not set")
-		}
-	default:
-		return "", "", fmt.Errorf("Invalid diario state: %s", state)
+	if r.Context().Err() != nil {
+		log.Printf("🔌 Client disconnected, chat stream canceled")
+		return
+	}
+
+	if err != nil {
+		log.Printf("❌ Failed to stream chat completion: %v", err)
+		stream.error("Failed to process chat completion")
+		stream.finish(finishError)
+		return
+	}
 
+	for _, citation := range answer.Citations {
+		stream.source(citation)
 	}
 
-	url = fmt.Sprintf("%s/api/v1/chat/completions", agentEndpoint)
-	return url, accessKey, nil
+	stream.finish(finishReason)
+}
+
+const selectDiarioStateTool = "select-diario-state"
 
+func newToolCallID() string {
+	return fmt.Sprintf("%d", time.Now().UnixMilli())
 }
 
 func selectDiarioStateToolCall() map[string]any {
@@ -232,8 +257,8 @@ func selectDiarioStateToolCall() map[string]any {
 		"content": []any{
 			map[string]any{
 				"type":       "tool-call",
-				"toolName":   "select-diario-state",
-				"toolCallId": fmt.Sprintf("%d", time.Now().UnixMilli()),
+				"toolName":   selectDiarioStateTool,
+				"toolCallId": newToolCallID(),
 				"argsText":   "",
 				"args":       map[string]any{},
 			},
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"radaroficial.app/internal/chat"
+)
+
+// Finish reasons of the data stream protocol
+const (
+	finishStop      = "stop"
+	finishToolCalls = "tool-calls"
+	finishError     = "error"
+)
+
+// dataStream writes a response in the data stream protocol of the AI SDK, read by the
+// assistant-ui runtime: one "code:JSON" part per line, flushed as soon as written so the
+// thread shows tokens as they are generated
+type dataStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// wantsStream reports whether a request asks for a streamed response with stream=true
+func wantsStream(r *http.Request) bool {
+	return r.URL.Query().Get("stream") == "true"
+}
+
+// newDataStream starts the response. The protocol streams plain text lines rather than
+// server-sent events, as the AI SDK expects.
+func newDataStream(w http.ResponseWriter) *dataStream {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.Header().Set("X-Vercel-AI-Data-Stream", "v1")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	return &dataStream{w: w, flusher: flusher}
+}
+
+func (s *dataStream) part(code string, value any) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(s.w, "%s:%s\n", code, encoded); err != nil {
+		return err
+	}
+
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return nil
+}
+
+// start opens the step of the assistant message
+func (s *dataStream) start(messageID string) error {
+	return s.part("f", map[string]string{"messageId": messageID})
+}
+
+// text appends text to the assistant message
+func (s *dataStream) text(text string) error {
+	return s.part("0", text)
+}
+
+// toolCall adds a tool call to the assistant message, args being a JSON object
+func (s *dataStream) toolCall(id, name, args string) error {
+	if !json.Valid([]byte(args)) {
+		args = "{}"
+	}
+
+	return s.part("9", map[string]any{
+		"toolCallId": id,
+		"toolName":   name,
+		"args":       json.RawMessage(args),
+	})
+}
+
+// source adds a citation to the assistant message, as a URL source and as data with
+// every field of the citation
+func (s *dataStream) source(citation chat.Citation) error {
+	title := citation.Title
+	if citation.Description != "" {
+		title += " (" + citation.Description + ")"
+	}
+
+	if err := s.part("h", map[string]string{
+		"sourceType": "url",
+		"id":         strconv.Itoa(citation.Number),
+		"url":        citation.URL,
+		"title":      title,
+	}); err != nil {
+		return err
+	}
+
+	return s.part("2", []any{map[string]any{"type": "citation", "citation": citation}})
+}
+
+// error reports a failure to the thread
+func (s *dataStream) error(message string) error {
+	return s.part("3", message)
+}
+
+// finish closes the step and the message
+func (s *dataStream) finish(reason string) error {
+	usage := map[string]int{"promptTokens": 0, "completionTokens": 0}
+
+	if err := s.part("e", map[string]any{"finishReason": reason, "usage": usage, "isContinued": false}); err != nil {
+		return err
+	}
+	return s.part("d", map[string]any{"finishReason": reason, "usage": usage})
+}
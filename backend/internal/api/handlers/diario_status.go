@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"radaroficial.app/internal/diarios"
+)
+
+// maxStuckDiarios bounds the number of diarios listed per request
+const maxStuckDiarios = 100
+
+type DiarioStatusHandler struct{ DB *pgxpool.Pool }
+
+func NewDiarioStatusHandler(db *pgxpool.Pool) *DiarioStatusHandler {
+	return &DiarioStatusHandler{DB: db}
+}
+
+type stuckDiario struct {
+	ID              int        `json:"id"`
+	Description     *string    `json:"description"`
+	Status          string     `json:"status"`
+	Version         int        `json:"version"`
+	StatusChangedAt *time.Time `json:"statusChangedAt"`
+	LastError       *string    `json:"lastError,omitempty"`
+	LastErrorAt     *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// ServeHTTP reports how many diarios are in each lifecycle status and how many have been
+// there for longer than olderThan (default 1h). With status, it lists those diarios.
+// Stuck diarios are re-driven hourly, failed ones with POST /jobs?redrive=failed.
+func (h *DiarioStatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != "GET" {
+		http.NotFound(w, r)
+		return
+	}
+
+	queryValues := r.URL.Query()
+
+	olderThan := time.Hour
+	if queryValues.Has("olderThan") {
+		d, err := time.ParseDuration(queryValues.Get("olderThan"))
+		if err != nil || d < 0 {
+			http.Error(w, "Invalid olderThan, expected a duration such as 30m or 2h", http.StatusBadRequest)
+			return
+		}
+		olderThan = d
+	}
+
+	srv := diarios.NewInstitutionService(h.DB)
+
+	if queryValues.Has("status") {
+		status := queryValues.Get("status")
+		if !slices.Contains(diarios.Statuses, status) {
+			http.Error(w, "Invalid status", http.StatusBadRequest)
+			return
+		}
+
+		stuck, err := srv.ListStuck(r.Context(), status, olderThan, maxStuckDiarios)
+		if err != nil {
+			log.Printf("❌ Failed to list stuck diarios: %v", err)
+			http.Error(w, "Failed to list diarios.", http.StatusInternalServerError)
+			return
+		}
+
+		items := make([]stuckDiario, 0, len(stuck))
+		for _, d := range stuck {
+			items = append(items, stuckDiario{
+				ID:              d.ID,
+				Description:     d.Description,
+				Status:          d.Status,
+				Version:         d.Version,
+				StatusChangedAt: d.StatusChangedAt,
+				LastError:       d.LastError,
+				LastErrorAt:     d.LastErrorAt,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"diarios": items,
+		})
+		return
+	}
+
+	counts, stuck, err := srv.CountByStatus(r.Context(), olderThan)
+	if err != nil {
+		log.Printf("❌ Failed to count diarios by status: %v", err)
+		http.Error(w, "Failed to count diarios.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"olderThan": olderThan.String(),
+		"counts":    counts,
+		"stuck":     stuck,
+	})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"radaroficial.app/internal/identifiers"
+)
+
+type IdentifierHandler struct {
+	identifierService *identifiers.IdentifierService
+}
+
+func NewIdentifierHandler(db *pgxpool.Pool) *IdentifierHandler {
+	return &IdentifierHandler{identifierService: identifiers.NewIdentifierService(db)}
+}
+
+// ServeHTTP looks up the atos citing an identifier, either given explicitly with
+// kind and value or extracted from a free text with q
+func (h *IdentifierHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != "GET" {
+		http.NotFound(w, r)
+		return
+	}
+
+	queryValues := r.URL.Query()
+
+	var matches []identifiers.Match
+	var err error
+
+	switch {
+	case queryValues.Has("q"):
+		matches, err = h.identifierService.LookupText(r.Context(), queryValues.Get("q"))
+	case queryValues.Has("kind") && queryValues.Has("value"):
+		kind := queryValues.Get("kind")
+		if !slices.Contains(identifiers.Kinds, kind) {
+			http.Error(w, fmt.Sprintf("Invalid kind, expected one of %s", strings.Join(identifiers.Kinds, ", ")), http.StatusBadRequest)
+			return
+		}
+
+		if identifiers.Normalize(kind, queryValues.Get("value")) == "" {
+			http.Error(w, "Invalid value for "+kind, http.StatusBadRequest)
+			return
+		}
+
+		matches, err = h.identifierService.Lookup(r.Context(), kind, queryValues.Get("value"))
+	default:
+		http.Error(w, "Missing q or kind and value parameters", http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		log.Printf("❌ Failed to look up identifiers: %v", err)
+		http.Error(w, "Failed to look up identifiers.", http.StatusInternalServerError)
+		return
+	}
+
+	if matches == nil {
+		matches = []identifiers.Match{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"matches": matches,
+	})
+}
@@ -3,15 +3,29 @@ package handlers
 import (
 	"log"
 	"net/http"
+	"strconv"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
+	"radaroficial.app/internal/diarios"
 	"radaroficial.app/internal/jobs"
 )
 
-type JobsHandler struct{ DB *pgxpool.Pool }
+// legacyJobNames maps the job names used before sources were registered by slug
+var legacyJobNames = map[string]string{
+	"doepi":         "governo-pi",
+	"municipios_pi": "municipios-pi",
+}
+
+// JobsHandler schedules jobs with an insert-only River client, the worker runs them
+type JobsHandler struct {
+	DB    *pgxpool.Pool
+	River *river.Client[pgx.Tx]
+}
 
-func NewJobsHandler(db *pgxpool.Pool) *JobsHandler {
-	return &JobsHandler{DB: db}
+func NewJobsHandler(db *pgxpool.Pool, riverClient *river.Client[pgx.Tx]) *JobsHandler {
+	return &JobsHandler{DB: db, River: riverClient}
 }
 
 func (h *JobsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -23,31 +37,109 @@ func (h *JobsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	queryValues := r.URL.Query()
 
+	// redrive re-enqueues the next stage of the diarios of every source in a status, e.g. failed
+	if queryValues.Has("redrive") {
+		if err := jobs.ScheduleRedriveDiariosJob(r.Context(), h.River, queryValues.Get("redrive")); err != nil {
+			log.Printf("❌ Failed to schedule re-drive job: %v", err)
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
 	if !queryValues.Has("name") {
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
 
-	jobName := queryValues.Get("name")
+	// the job name is the slug of the institution whose source should be fetched
+	slug := queryValues.Get("name")
+	if legacy, ok := legacyJobNames[slug]; ok {
+		slug = legacy
+	}
 
-	riverClient, err := jobs.NewRiverClient(r.Context(), h.DB)
-	if err != nil {
-		log.Printf("❌ Failed to initialize River Queue client: %v", err)
-		http.Error(w, "server error", http.StatusInternalServerError)
+	if _, ok := diarios.GetSource(slug); !ok {
+		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
 
-	switch jobName {
-	case "doepi":
-		// YYY-MM-DD
-		customDate := queryValues.Get("customDate") // defaults to "" if no custom date set, which is interpreted as the current date
-		jobs.ScheduleGovernoPiauiJob(r.Context(), riverClient.Client, customDate)
+	// an edition number fetches that single edition
+	if queryValues.Has("edition") {
+		number, err := strconv.Atoi(queryValues.Get("edition"))
+		if err == nil {
+			err = jobs.ScheduleFetchEditionJob(r.Context(), h.River, slug, number)
+		}
+		if err != nil {
+			log.Printf("❌ Failed to schedule edition job: %v", err)
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
 		w.WriteHeader(http.StatusNoContent)
-	case "municipios_pi":
-		jobs.ScheduleDiarioDosMunicipiosJob(r.Context(), riverClient.Client)
+		return
+	}
+
+	// reindex replaces the weaviate objects of a single diario of the institution
+	if queryValues.Has("reindex") {
+		diarioID, err := strconv.Atoi(queryValues.Get("reindex"))
+		if err == nil {
+			err = jobs.ScheduleIndexDiarioJob(r.Context(), h.River, diarios.NewInstitutionService(h.DB), slug, diarioID)
+		}
+		if err != nil {
+			log.Printf("❌ Failed to schedule reindex job: %v", err)
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
 		w.WriteHeader(http.StatusNoContent)
-	default:
-		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	// fillGaps enqueues the editions missing since firstEdition, or within the last year
+	if queryValues.Get("fillGaps") == "true" {
+		firstEdition := 0
+		if queryValues.Has("firstEdition") {
+			var err error
+			firstEdition, err = strconv.Atoi(queryValues.Get("firstEdition"))
+			if err != nil || firstEdition < 1 {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := jobs.ScheduleFillEditionGapsJob(r.Context(), h.River, slug, firstEdition); err != nil {
+			log.Printf("❌ Failed to schedule gap fill job: %v", err)
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// a startDate/endDate pair (YYYY-MM-DD) schedules a historical backfill
+	if queryValues.Has("startDate") || queryValues.Has("endDate") {
+		err := jobs.ScheduleBackfillJob(r.Context(), h.River, slug,
+			queryValues.Get("startDate"), queryValues.Get("endDate"))
+		if err != nil {
+			log.Printf("❌ Failed to schedule backfill: %v", err)
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// YYY-MM-DD
+	customDate := queryValues.Get("customDate") // defaults to "" if no custom date set, which is interpreted as the current date
+	if err := jobs.ScheduleFetchDiariosJob(r.Context(), h.River, slug, customDate); err != nil {
+		log.Printf("❌ Failed to schedule job: %v", err)
+		http.Error(w, "server error", http.StatusInternalServerError)
+		return
 	}
 
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"radaroficial.app/internal/search"
+)
+
+type SearchHandler struct {
+	hybridService *search.HybridService
+}
+
+func NewSearchHandler(db *pgxpool.Pool, semantic search.Retriever) *SearchHandler {
+	return &SearchHandler{hybridService: search.NewHybridService(db, semantic)}
+}
+
+// ServeHTTP searches atos by keywords and meaning, with q in the syntax of
+// search.ParseQuery, filtered as search.FilterFromQuery and paginated by limit and the
+// nextCursor of the previous page
+func (h *SearchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != "GET" {
+		http.NotFound(w, r)
+		return
+	}
+
+	queryValues := r.URL.Query()
+
+	q := strings.TrimSpace(queryValues.Get("q"))
+	if q == "" {
+		http.Error(w, "Missing q parameter", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := search.FilterFromQuery(queryValues)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit, _, err := pageParams(queryValues.Get("limit"), "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	offset := 0
+	if cursor := queryValues.Get("cursor"); cursor != "" {
+		offset, err = decodeCursor(cursor, q, filter)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	hits, more, err := h.hybridService.Search(r.Context(), q, filter, limit, offset)
+	if errors.Is(err, search.ErrEmptyQuery) {
+		http.Error(w, "Nothing to search in q", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to search atos: %v", err)
+		http.Error(w, "Failed to search atos.", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]any{
+		"results":    hits,
+		"nextCursor": nil,
+	}
+	if more {
+		response["nextCursor"] = encodeCursor(offset+len(hits), q, filter)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// encodeCursor returns an opaque cursor to the results after offset, only valid for
+// the same query and filter
+func encodeCursor(offset int, q string, filter search.Filter) string {
+	return base64.RawURLEncoding.EncodeToString(
+		[]byte(strconv.Itoa(offset) + "." + cursorFingerprint(q, filter)))
+}
+
+// decodeCursor returns the offset of a cursor created by encodeCursor
+func decodeCursor(cursor, q string, filter search.Filter) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	offsetPart, fingerprint, ok := strings.Cut(string(decoded), ".")
+	if !ok || fingerprint != cursorFingerprint(q, filter) {
+		return 0, errors.New("cursor of another search")
+	}
+
+	offset, err := strconv.Atoi(offsetPart)
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid cursor offset")
+	}
+
+	return offset, nil
+}
+
+func cursorFingerprint(q string, filter search.Filter) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(q + "\x00" + filter.String()))
+	return strconv.FormatUint(uint64(hash.Sum32()), 36)
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"radaroficial.app/internal/search"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type TextSearchHandler struct {
+	fullTextService *search.FullTextService
+}
+
+func NewTextSearchHandler(db *pgxpool.Pool) *TextSearchHandler {
+	return &TextSearchHandler{fullTextService: search.NewFullTextService(db)}
+}
+
+// ServeHTTP searches the text of the atos literally, with q in the syntax of
+// search.ParseQuery, filtered as search.FilterFromQuery and paginated by limit and offset
+func (h *TextSearchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != "GET" {
+		http.NotFound(w, r)
+		return
+	}
+
+	queryValues := r.URL.Query()
+
+	q := strings.TrimSpace(queryValues.Get("q"))
+	if q == "" {
+		http.Error(w, "Missing q parameter", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := search.FilterFromQuery(queryValues)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := pageParams(queryValues.Get("limit"), queryValues.Get("offset"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hits, total, err := h.fullTextService.Search(r.Context(), q, filter, limit, offset)
+	if errors.Is(err, search.ErrEmptyQuery) {
+		http.Error(w, "Nothing to search in q", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Failed to search atos: %v", err)
+		http.Error(w, "Failed to search atos.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"results": hits,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// pageParams parses the limit and offset of a page of results
+func pageParams(limitParam, offsetParam string) (limit, offset int, err error) {
+	limit = defaultSearchLimit
+	if limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			return 0, 0, errors.New("Invalid limit, expected 1 to " + strconv.Itoa(maxSearchLimit))
+		}
+	}
+
+	if offsetParam != "" {
+		offset, err = strconv.Atoi(offsetParam)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("Invalid offset")
+		}
+	}
+
+	return limit, offset, nil
+}
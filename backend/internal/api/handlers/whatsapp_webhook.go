@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,17 +10,25 @@ import (
 	"strings"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"radaroficial.app/internal/chat"
+	"radaroficial.app/internal/search"
 	"radaroficial.app/internal/whatsapp"
 )
 
 // WhatsAppWebhookHandler handles incoming webhook requests from WhatsApp
 type WhatsAppWebhookHandler struct {
 	whatsappService *whatsapp.WhatsAppService
+	chatService     *chat.ChatService
 	db              *pgxpool.Pool
 }
 
+// whatsappStates maps the state selected in the WhatsApp list to its code
+var whatsappStates = map[string]string{
+	"piaui": "PI",
+}
+
 // NewWhatsAppWebhookHandler creates a new WhatsAppWebhookHandler
-func NewWhatsAppWebhookHandler(db *pgxpool.Pool) (*WhatsAppWebhookHandler, error) {
+func NewWhatsAppWebhookHandler(db *pgxpool.Pool, semantic search.Retriever) (*WhatsAppWebhookHandler, error) {
 	whatsappService, err := whatsapp.NewWhatsAppService(db)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create WhatsApp service: %w", err)
@@ -29,6 +36,7 @@ func NewWhatsAppWebhookHandler(db *pgxpool.Pool) (*WhatsAppWebhookHandler, error
 
 	return &WhatsAppWebhookHandler{
 		whatsappService: whatsappService,
+		chatService:     chat.NewChatService(db, semantic),
 		db:              db,
 	}, nil
 }
@@ -150,6 +158,7 @@ func (h *WhatsAppWebhookHandler) handleWebhook(w http.ResponseWriter, r *http.Re
 				// Collect all message texts from this change
 				var allMessages []string
 				var senderID string
+				var userState string
 				var isFirstMessage bool = false
 
 				// Extract sender ID first to check user session
@@ -157,7 +166,8 @@ func (h *WhatsAppWebhookHandler) handleWebhook(w http.ResponseWriter, r *http.Re
 					senderID = change.Value.Messages[0].From
 
 					// Check if this is a first-time interaction by checking user session state
-					userState, err := h.whatsappService.GetUserState(ctx, senderID)
+					var err error
+					userState, err = h.whatsappService.GetUserState(ctx, senderID)
 					if err != nil || userState == "" {
 						// User has no state or session, consider it a first message
 						isFirstMessage = true
@@ -231,15 +241,18 @@ func (h *WhatsAppWebhookHandler) handleWebhook(w http.ResponseWriter, r *http.Re
 					// Combine all messages into a single string
 					combinedMessage := strings.Join(allMessages, "\n")
 
-					// Send the combined message to the AI agent
-					agentResponse, err := h.sendMessageToAIAgent(combinedMessage)
+					// Answer the combined message from the atos of the state the user selected
+					answer, err := h.chatService.Answer(ctx, chat.Question{
+						Text:   combinedMessage,
+						Filter: search.Filter{State: whatsappStates[userState]},
+					})
 					if err != nil {
-						log.Printf("❌ Error sending message to AI agent: %v", err)
+						log.Printf("❌ Error answering message: %v", err)
 						responseText := "Desculpe, estamos com dificuldades técnicas. Tente novamente mais tarde."
 						h.whatsappService.SendTextMessage(senderID, responseText)
 					} else {
-						// Send the AI response back to the user
-						h.whatsappService.SendTextMessage(senderID, agentResponse)
+						// Send the answer back to the user
+						h.whatsappService.SendTextMessage(senderID, whatsapp.FormatAnswer(answer))
 					}
 				}
 			}
@@ -250,108 +263,4 @@ func (h *WhatsAppWebhookHandler) handleWebhook(w http.ResponseWriter, r *http.Re
 	w.WriteHeader(http.StatusOK)
 }
 
-// sendMessageToAIAgent sends a message to the Digital Ocean AI agent and returns the response
-func (h *WhatsAppWebhookHandler) sendMessageToAIAgent(message string) (string, error) {
-	// Get endpoint and access key from environment variables
-	agentEndpoint := os.Getenv("DO_AGENT_PIAUI_URL")
-	if agentEndpoint == "" {
-		return "", fmt.Errorf("DO_AGENT_PIAUI_URL environment variable not set")
-	}
-
-	agentAccessKey := os.Getenv("DO_AGENT_PIAUI_ACCESS_KEY")
-	if agentAccessKey == "" {
-		return "", fmt.Errorf("DO_AGENT_PIAUI_ACCESS_KEY environment variable not set")
-	}
-
-	// Construct the url for the request
-	url := fmt.Sprintf("%s/api/v1/chat/completions", agentEndpoint)
-
-	// Construct the request payload
-	type Message struct {
-		Role    string `json:"role"`
-		Content string `json:"content"`
-	}
-
-	payload := struct {
-		Messages              []Message `json:"messages"`
-		Stream                bool      `json:"stream"`
-		IncludeFunctionsInfo  bool      `json:"include_functions_info"`
-		IncludeRetrievalInfo  bool      `json:"include_retrieval_info"`
-		IncludeGuardrailsInfo bool      `json:"include_guardrails_info"`
-	}{
-		Messages: []Message{
-			{
-				Role:    "user",
-				Content: message,
-			},
-		},
-		Stream:                false,
-		IncludeFunctionsInfo:  false,
-		IncludeRetrievalInfo:  false,
-		IncludeGuardrailsInfo: false,
-	}
-
-	jsonPayload, err := json.Marshal(payload)
-	if err != nil {
-		log.Printf("❌ Error marshaling AI agent payload: %v", err)
-		return "", err
-	}
-
-	// Create the request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		log.Printf("❌ Error creating AI agent request: %v", err)
-		return "", err
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+agentAccessKey)
-
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("❌ Error sending message to AI agent: %v", err)
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	// Check for errors
-	if resp.StatusCode >= 400 {
-		responseBody, _ := io.ReadAll(resp.Body)
-		log.Printf("❌ AI agent API error (status %d): %s", resp.StatusCode, string(responseBody))
-		return "", fmt.Errorf("AI agent API error: %d", resp.StatusCode)
-	}
-
-	// Parse the response
-	type AIResponse struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
-	}
-
-	var aiResponse AIResponse
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("❌ Error reading AI agent response: %v", err)
-		return "", err
-	}
-
-	if err := json.Unmarshal(responseBody, &aiResponse); err != nil {
-		log.Printf("❌ Error parsing AI agent response: %v", err)
-		return "", err
-	}
-
-	// Extract the response message
-	if len(aiResponse.Choices) == 0 {
-		return "", fmt.Errorf("no response from AI agent")
-	}
-
-	log.Printf("✅ Got response from AI agent: %s", aiResponse.Choices[0].Message.Content)
-	return aiResponse.Choices[0].Message.Content, nil
-}
-
 // This function has been moved to the WhatsAppService
@@ -6,31 +6,47 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
 	"radaroficial.app/internal/api/handlers"
+	"radaroficial.app/internal/vectorstore"
 )
 
 type Server struct {
-	DB     *pgxpool.Pool
-	Router *http.ServeMux
-	server *http.Server
+	DB          *pgxpool.Pool
+	VectorStore vectorstore.VectorStore // Ranks atos by meaning in search and chat
+	River       *river.Client[pgx.Tx]   // Insert-only, jobs are worked by the worker
+	Router      *http.ServeMux
+	server      *http.Server
 }
 
-func NewServer(db *pgxpool.Pool) *Server {
+// NewServer creates a new Server. The vector store and the River client are created by
+// the caller, which fails to start without them rather than serving keyword-only results.
+func NewServer(db *pgxpool.Pool, vectorStore vectorstore.VectorStore, riverClient *river.Client[pgx.Tx]) *Server {
 	return &Server{
-		DB:     db,
-		Router: http.NewServeMux(),
+		DB:          db,
+		VectorStore: vectorStore,
+		River:       riverClient,
+		Router:      http.NewServeMux(),
 	}
 }
 
 func (s *Server) RegisterHandlers() {
 
-	s.Router.Handle("/chat", handlers.WithCORS(handlers.NewChatHandler(s.DB)))
+	s.Router.Handle("/chat", handlers.WithCORS(handlers.NewChatHandler(s.DB, s.VectorStore)))
 	s.Router.Handle("/states", handlers.WithCORS(handlers.NewStateHandler(s.DB)))
-	s.Router.Handle("/jobs", handlers.NewJobsHandler(s.DB))
+	s.Router.Handle("/identifiers", handlers.WithCORS(handlers.NewIdentifierHandler(s.DB)))
+	s.Router.Handle("/search", handlers.WithCORS(handlers.NewSearchHandler(s.DB, s.VectorStore)))
+	s.Router.Handle("/search/text", handlers.WithCORS(handlers.NewTextSearchHandler(s.DB)))
+	s.Router.Handle("/institutions", handlers.WithCORS(handlers.NewInstitutionsHandler(s.DB)))
+	s.Router.Handle("/institutions/{slug}/diarios", handlers.WithCORS(handlers.NewInstitutionDiariosHandler(s.DB)))
+	s.Router.Handle("/diarios/{id}", handlers.WithCORS(handlers.NewDiarioHandler(s.DB)))
+	s.Router.Handle("/jobs", handlers.NewJobsHandler(s.DB, s.River))
+	s.Router.Handle("/diarios/status", handlers.NewDiarioStatusHandler(s.DB))
 
 	// Initialize WhatsApp webhook handler
-	whatsappHandler, err := handlers.NewWhatsAppWebhookHandler(s.DB)
+	whatsappHandler, err := handlers.NewWhatsAppWebhookHandler(s.DB, s.VectorStore)
 	if err == nil {
 		s.Router.Handle("/webhook/whatsapp", whatsappHandler)
 	} else {
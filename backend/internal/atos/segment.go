@@ -0,0 +1,358 @@
+package atos
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"radaroficial.app/internal/extract"
+	"radaroficial.app/internal/model"
+)
+
+// Act types recognized at the start of a heading line, longest first so that
+// "LEI COMPLEMENTAR" wins over "LEI"
+var actTypes = []struct {
+	prefix  string
+	actType string
+}{
+	{"INSTRUÇÃO NORMATIVA", "instrucao_normativa"},
+	{"LEI COMPLEMENTAR", "lei_complementar"},
+	{"LEI ORDINÁRIA", "lei"},
+	{"MEDIDA PROVISÓRIA", "medida_provisoria"},
+	{"PORTARIA", "portaria"},
+	{"DECRETO", "decreto"},
+	{"RESOLUÇÃO", "resolucao"},
+	{"EXTRATO", "extrato"},
+	{"AVISO", "aviso"},
+	{"EDITAL", "edital"},
+	{"TERMO", "termo"},
+	{"DESPACHO", "despacho"},
+	{"ERRATA", "errata"},
+	{"RETIFICAÇÃO", "retificacao"},
+	{"DELIBERAÇÃO", "deliberacao"},
+	{"ACÓRDÃO", "acordao"},
+	{"CONVOCAÇÃO", "convocacao"},
+	{"HOMOLOGAÇÃO", "homologacao"},
+	{"ADJUDICAÇÃO", "adjudicacao"},
+	{"RATIFICAÇÃO", "ratificacao"},
+	{"CONTRATO", "contrato"},
+	{"LEI", "lei"},
+	{"ATO", "ato"},
+}
+
+// Issuing bodies that head a group of acts in DOE-PI and municipal editions
+var orgaoPrefixes = []string{
+	"SECRETARIA", "PREFEITURA MUNICIPAL", "CÂMARA MUNICIPAL", "GOVERNO DO ESTADO",
+	"GABINETE", "FUNDAÇÃO", "INSTITUTO", "UNIVERSIDADE", "DEPARTAMENTO", "AGÊNCIA",
+	"COMPANHIA", "EMPRESA", "TRIBUNAL", "DEFENSORIA", "PROCURADORIA", "CONTROLADORIA",
+	"MINISTÉRIO PÚBLICO", "ASSEMBLEIA LEGISLATIVA", "POLÍCIA", "CORPO DE BOMBEIROS",
+	"CONSELHO", "FUNDO MUNICIPAL", "SERVIÇO AUTÔNOMO", "COORDENADORIA", "SUPERINTENDÊNCIA",
+}
+
+var (
+	numberRegexp       = regexp.MustCompile(`(?i)\bN\.?\s*[º°o]?\s*[:.]?\s*(\d[\d./-]*\d|\d)`)
+	yearRegexp         = regexp.MustCompile(`\b(19\d{2}|20\d{2})\b`)
+	markdownPrefix     = regexp.MustCompile(`^[#>*_|\-\s]+`)
+	municipalityRegexp = regexp.MustCompile(`(?i)(?:PREFEITURA|CÂMARA) MUNICIPAL DE\s+([^,;–/()-]+)`)
+)
+
+const (
+	// maxHeadingLength bounds the length of lines considered as act or órgão headings
+	maxHeadingLength = 200
+
+	// runningHeaderLines is how many lines at the top and bottom of a page may be
+	// repeated page headers and footers
+	runningHeaderLines = 3
+)
+
+var digitsRegexp = regexp.MustCompile(`\d+`)
+
+type segmentLine struct {
+	text string
+	page int
+	ocr  bool
+	conf float64
+}
+
+// Segment splits the pages of an edition into individual acts. Lines before the first
+// act become a "preambulo" act, text under a later órgão header without an act heading
+// an act of type "outro", and when no heading is recognized at all each page becomes an
+// act of type "pagina" so nothing is left out of the index. Page headers and footers
+// repeated across pages are only kept at the top of the first page, so an act continues
+// across page breaks.
+func Segment(pages []extract.Page) []model.Ato {
+	headers := runningHeaders(pages)
+
+	// A running header is kept at the top of the page where it first appears, as the
+	// masthead of the edition or the órgão of its first acts. Footers are left out.
+	kept := map[string]bool{}
+
+	var lines []segmentLine
+	for _, page := range pages {
+		texts := pageLines(page)
+		for i, text := range texts {
+			if key := runningKey(text); headers[key] {
+				top := i < runningHeaderLines
+				if top && !kept[key] {
+					kept[key] = true
+				} else if top || i >= len(texts)-runningHeaderLines {
+					continue
+				}
+			}
+			lines = append(lines, segmentLine{text: text, page: page.Number, ocr: page.OCR, conf: page.OCRConfidence})
+		}
+	}
+
+	var atos []model.Ato
+	var current *model.Ato
+	var body []string
+	var orgao *string
+	foundAct := false
+
+	closeCurrent := func() {
+		if current != nil {
+			current.Content = strings.TrimSpace(strings.Join(body, "\n"))
+			if current.Content != "" {
+				current.Seq = len(atos)
+				atos = append(atos, *current)
+			}
+		}
+		current = nil
+		body = nil
+	}
+
+	for _, line := range lines {
+		heading := cleanHeading(line.text)
+
+		if isOrgaoHeading(heading) {
+			if orgao != nil && *orgao == heading {
+				// The órgão repeated at the top of a page, its act goes on
+				continue
+			}
+			closeCurrent()
+			name := heading
+			orgao = &name
+			continue
+		}
+
+		if actType, ok := matchActType(heading); ok {
+			closeCurrent()
+			foundAct = true
+			current = &model.Ato{
+				Orgao:     orgao,
+				ActType:   actType,
+				Title:     heading,
+				PageStart: line.page,
+				PageEnd:   line.page,
+			}
+			current.Number, current.Year = parseNumberYear(heading)
+		} else if current == nil {
+			// Text before the first act of the edition, or under an órgão header
+			actType := "preambulo"
+			if foundAct {
+				actType = "outro"
+			}
+			current = &model.Ato{
+				Orgao:     orgao,
+				ActType:   actType,
+				Title:     heading,
+				PageStart: line.page,
+				PageEnd:   line.page,
+			}
+		}
+
+		current.PageEnd = line.page
+		if line.ocr {
+			markOCR(current, line.conf)
+		}
+		body = append(body, line.text)
+	}
+	closeCurrent()
+
+	if !foundAct {
+		return pagesAsAtos(pages)
+	}
+
+	return atos
+}
+
+// pageLines returns the non-blank lines of a page, trimmed
+func pageLines(page extract.Page) []string {
+	var lines []string
+	for _, text := range strings.Split(page.Markdown, "\n") {
+		if text = strings.TrimSpace(text); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	return lines
+}
+
+// runningHeaders returns the keys (see runningKey) of the lines repeated at the top or
+// bottom of at least half the pages of an edition, such as "GOVERNO DO ESTADO DO PIAUÍ"
+// or the date and number of the edition. Act headings are never running headers.
+func runningHeaders(pages []extract.Page) map[string]bool {
+	counts := map[string]int{}
+	for _, page := range pages {
+		texts := pageLines(page)
+
+		seen := map[string]bool{}
+		for i, text := range texts {
+			if i >= runningHeaderLines && i < len(texts)-runningHeaderLines {
+				continue
+			}
+			if _, ok := matchActType(cleanHeading(text)); ok {
+				continue
+			}
+
+			key := runningKey(text)
+			if !seen[key] {
+				seen[key] = true
+				counts[key]++
+			}
+		}
+	}
+
+	headers := map[string]bool{}
+	for key, count := range counts {
+		if count >= 2 && count*2 >= len(pages) {
+			headers[key] = true
+		}
+	}
+	return headers
+}
+
+// runningKey identifies a line among page headers, ignoring the numbers that change from
+// page to page, as in "Página 3 de 40"
+func runningKey(text string) string {
+	return digitsRegexp.ReplaceAllString(cleanHeading(text), "0")
+}
+
+// pagesAsAtos turns every non-empty page into an act
+func pagesAsAtos(pages []extract.Page) []model.Ato {
+	var atos []model.Ato
+	for _, page := range pages {
+		content := strings.TrimSpace(page.Markdown)
+		if content == "" {
+			continue
+		}
+
+		ato := model.Ato{
+			Seq:       len(atos),
+			ActType:   "pagina",
+			Title:     "Página " + strconv.Itoa(page.Number),
+			Content:   content,
+			PageStart: page.Number,
+			PageEnd:   page.Number,
+		}
+		if page.OCR {
+			markOCR(&ato, page.OCRConfidence)
+		}
+		atos = append(atos, ato)
+	}
+	return atos
+}
+
+// markOCR flags an act as OCR'd, keeping the lowest confidence among its pages
+func markOCR(ato *model.Ato, confidence float64) {
+	if !ato.OCR || ato.OCRConfidence == nil || confidence < *ato.OCRConfidence {
+		c := confidence
+		ato.OCRConfidence = &c
+	}
+	ato.OCR = true
+}
+
+// cleanHeading strips Markdown markers and collapses spaces
+func cleanHeading(line string) string {
+	line = markdownPrefix.ReplaceAllString(line, "")
+	line = strings.TrimRight(line, "*_| ")
+	return strings.Join(strings.Fields(line), " ")
+}
+
+// matchActType recognizes act headings such as "PORTARIA Nº 123/2025" or
+// "EXTRATO DO CONTRATO Nº 10/2025". The type must be written in uppercase,
+// otherwise a sentence mentioning "a portaria nº 12" would start a new act.
+func matchActType(heading string) (string, bool) {
+	if heading == "" || len(heading) > maxHeadingLength {
+		return "", false
+	}
+
+	for _, t := range actTypes {
+		if !strings.HasPrefix(heading, t.prefix) {
+			continue
+		}
+
+		rest := heading[len(t.prefix):]
+		if rest != "" {
+			r := []rune(rest)[0]
+			if unicode.IsLetter(r) {
+				continue // e.g. "LEILÃO", "ATOS"
+			}
+		}
+
+		// Bare words like "AVISO" must carry a number, a date or a qualifier to be a heading
+		if strings.TrimSpace(rest) == "" && t.actType != "errata" && t.actType != "retificacao" {
+			return "", false
+		}
+
+		return t.actType, true
+	}
+
+	return "", false
+}
+
+// isOrgaoHeading recognizes uppercase órgão headers such as "SECRETARIA DA SAÚDE"
+func isOrgaoHeading(heading string) bool {
+	if heading == "" || len(heading) > maxHeadingLength || !isUpperText(heading) {
+		return false
+	}
+
+	for _, prefix := range orgaoPrefixes {
+		if strings.HasPrefix(heading, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNumberYear extracts "Nº 123/2025" style numbers and the year of an act heading
+func parseNumberYear(heading string) (*string, *int) {
+	var number *string
+	var year *int
+
+	if m := numberRegexp.FindStringSubmatch(heading); len(m) == 2 {
+		n := strings.TrimRight(m[1], "./-")
+		number = &n
+	}
+
+	if m := yearRegexp.FindAllString(heading, -1); len(m) > 0 {
+		if y, err := strconv.Atoi(m[len(m)-1]); err == nil {
+			year = &y
+		}
+	}
+
+	return number, year
+}
+
+func isUpperText(s string) bool {
+	letters := 0
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			letters++
+			if !unicode.IsUpper(r) {
+				return false
+			}
+		}
+	}
+	return letters > 0
+}
+
+// Municipality returns the municipality named by an órgão header such as
+// "PREFEITURA MUNICIPAL DE PICOS - PI", or "" for bodies of the state
+func Municipality(orgao string) string {
+	m := municipalityRegexp.FindStringSubmatch(orgao)
+	if m == nil {
+		return ""
+	}
+	return strings.TrimSpace(m[1])
+}
@@ -0,0 +1,249 @@
+package atos
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"radaroficial.app/internal/extract"
+)
+
+// doePage is a page of the DOE-PI as converted to Markdown, under its running header
+func doePage(number int, lines ...string) extract.Page {
+	header := []string{
+		"# GOVERNO DO ESTADO DO PIAUÍ",
+		"## DIÁRIO OFICIAL",
+		"Teresina(PI) - Terça-feira, 3 de junho de 2025 • Edição nº 105",
+	}
+	footer := []string{"Página " + strconv.Itoa(number) + " de 3"}
+
+	return extract.Page{Number: number, Markdown: strings.Join(append(append(header, lines...), footer...), "\n\n")}
+}
+
+func page(number int, lines ...string) extract.Page {
+	return extract.Page{Number: number, Markdown: strings.Join(lines, "\n\n")}
+}
+
+type wantAto struct {
+	actType   string
+	title     string
+	orgao     string
+	pageStart int
+	pageEnd   int
+	contains  []string
+	excludes  []string
+}
+
+func TestSegment(t *testing.T) {
+	tests := []struct {
+		name  string
+		pages []extract.Page
+		want  []wantAto
+	}{
+		{
+			name: "DOE-PI act continued across a page break",
+			pages: []extract.Page{
+				doePage(1,
+					"### SECRETARIA DE ESTADO DA SAÚDE",
+					"### PORTARIA Nº 123/2025 - GAB/SESAPI",
+					"O SECRETÁRIO DE ESTADO DA SAÚDE DO PIAUÍ, no uso de suas atribuições legais, RESOLVE:",
+					"Art. 1º Nomear MARIA DA SILVA para o cargo em comissão de Assessora Técnica,",
+				),
+				doePage(2,
+					"símbolo DAS-3, da Superintendência de Atenção Primária.",
+					"Art. 2º Esta portaria entra em vigor na data de sua publicação.",
+					"### SECRETARIA DE ESTADO DA EDUCAÇÃO",
+					"### PORTARIA Nº 45/2025",
+					"Art. 1º Designar JOÃO PEREIRA para responder pela Gerência Regional de Picos.",
+				),
+				doePage(3,
+					"Teresina, 2 de junho de 2025.",
+					"### EXTRATO DO CONTRATO Nº 12/2025",
+					"Objeto: aquisição de material de expediente. Valor: R$ 10.500,00.",
+				),
+			},
+			want: []wantAto{
+				{
+					actType: "preambulo", title: "DIÁRIO OFICIAL", orgao: "GOVERNO DO ESTADO DO PIAUÍ",
+					pageStart: 1, pageEnd: 1,
+					contains: []string{"Edição nº 105"},
+				},
+				{
+					actType: "portaria", title: "PORTARIA Nº 123/2025 - GAB/SESAPI", orgao: "SECRETARIA DE ESTADO DA SAÚDE",
+					pageStart: 1, pageEnd: 2,
+					contains: []string{"Assessora Técnica,", "símbolo DAS-3", "entra em vigor"},
+					excludes: []string{"GOVERNO DO ESTADO", "DIÁRIO OFICIAL", "Edição nº 105", "Página"},
+				},
+				{
+					actType: "portaria", title: "PORTARIA Nº 45/2025", orgao: "SECRETARIA DE ESTADO DA EDUCAÇÃO",
+					pageStart: 2, pageEnd: 3,
+					contains: []string{"Gerência Regional de Picos", "Teresina, 2 de junho de 2025."},
+				},
+				{
+					actType: "extrato", title: "EXTRATO DO CONTRATO Nº 12/2025", orgao: "SECRETARIA DE ESTADO DA EDUCAÇÃO",
+					pageStart: 3, pageEnd: 3,
+					contains: []string{"material de expediente"},
+				},
+			},
+		},
+		{
+			name: "municipal edition with a preamble and órgão headers",
+			pages: []extract.Page{
+				page(1,
+					"# DIÁRIO OFICIAL DOS MUNICÍPIOS",
+					"Edição VI - Nº 5.321",
+					"**PREFEITURA MUNICIPAL DE PICOS - PI**",
+					"**DECRETO Nº 10/2025**",
+					"Dispõe sobre o ponto facultativo. O PREFEITO MUNICIPAL DE PICOS, no uso de suas atribuições,",
+					"DECRETA: Art. 1º Fica declarado ponto facultativo, conforme a portaria nº 12/2025.",
+					"**CÂMARA MUNICIPAL DE OEIRAS - PI**",
+					"A Mesa Diretora torna pública a pauta da sessão ordinária de 5 de junho.",
+				),
+			},
+			want: []wantAto{
+				{actType: "preambulo", title: "DIÁRIO OFICIAL DOS MUNICÍPIOS", pageStart: 1, pageEnd: 1, contains: []string{"Nº 5.321"}},
+				{
+					actType: "decreto", title: "DECRETO Nº 10/2025", orgao: "PREFEITURA MUNICIPAL DE PICOS - PI",
+					pageStart: 1, pageEnd: 1,
+					contains: []string{"ponto facultativo", "a portaria nº 12/2025"},
+				},
+				{
+					actType: "outro", title: "A Mesa Diretora torna pública a pauta da sessão ordinária de 5 de junho.",
+					orgao: "CÂMARA MUNICIPAL DE OEIRAS - PI", pageStart: 1, pageEnd: 1,
+				},
+			},
+		},
+		{
+			name: "órgão repeated at the top of the next page",
+			pages: []extract.Page{
+				page(1,
+					"SECRETARIA DE ESTADO DA FAZENDA",
+					"AVISO DE LICITAÇÃO - PREGÃO ELETRÔNICO Nº 7/2025",
+					"Objeto: contratação de empresa para manutenção predial,",
+				),
+				page(2,
+					"SECRETARIA DE ESTADO DA FAZENDA",
+					"com abertura das propostas em 20 de junho de 2025.",
+				),
+			},
+			want: []wantAto{
+				{
+					actType: "aviso", title: "AVISO DE LICITAÇÃO - PREGÃO ELETRÔNICO Nº 7/2025", orgao: "SECRETARIA DE ESTADO DA FAZENDA",
+					pageStart: 1, pageEnd: 2,
+					contains: []string{"manutenção predial,", "abertura das propostas"},
+				},
+			},
+		},
+		{
+			name: "pages without act headings",
+			pages: []extract.Page{
+				page(1, "Relação de servidores convocados para a perícia médica."),
+				page(2),
+				page(3, "Lista de aprovados no processo seletivo simplificado."),
+			},
+			want: []wantAto{
+				{actType: "pagina", title: "Página 1", pageStart: 1, pageEnd: 1},
+				{actType: "pagina", title: "Página 3", pageStart: 3, pageEnd: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Segment(tt.pages)
+
+			if len(got) != len(tt.want) {
+				for _, a := range got {
+					t.Logf("%s %q: %q", a.ActType, a.Title, a.Content)
+				}
+				t.Fatalf("got %d atos, want %d", len(got), len(tt.want))
+			}
+
+			for i, want := range tt.want {
+				a := got[i]
+				orgao := ""
+				if a.Orgao != nil {
+					orgao = *a.Orgao
+				}
+
+				if a.Seq != i || a.ActType != want.actType || a.Title != want.title || orgao != want.orgao ||
+					a.PageStart != want.pageStart || a.PageEnd != want.pageEnd {
+					t.Errorf("ato %d = {%d %s %q %q pages %d-%d}, want {%d %s %q %q pages %d-%d}", i,
+						a.Seq, a.ActType, a.Title, orgao, a.PageStart, a.PageEnd,
+						i, want.actType, want.title, want.orgao, want.pageStart, want.pageEnd)
+				}
+				for _, s := range want.contains {
+					if !strings.Contains(a.Content, s) {
+						t.Errorf("ato %d does not contain %q: %q", i, s, a.Content)
+					}
+				}
+				for _, s := range want.excludes {
+					if strings.Contains(a.Content, s) {
+						t.Errorf("ato %d contains %q: %q", i, s, a.Content)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMatchActType(t *testing.T) {
+	tests := []struct {
+		heading string
+		want    string
+	}{
+		{"PORTARIA Nº 123/2025 - GAB/SESAPI", "portaria"},
+		{"LEI COMPLEMENTAR Nº 301, DE 2 DE JUNHO DE 2025", "lei_complementar"},
+		{"LEI Nº 8.123, DE 2 DE JUNHO DE 2025", "lei"},
+		{"EXTRATO DO CONTRATO Nº 12/2025", "extrato"},
+		{"ERRATA", "errata"},
+		{"AVISO", ""},
+		{"LEILÃO Nº 2/2025", ""},
+		{"ATOS DO GOVERNADOR", ""},
+		{"Portaria nº 123/2025", ""},
+	}
+
+	for _, tt := range tests {
+		got, _ := matchActType(tt.heading)
+		if got != tt.want {
+			t.Errorf("matchActType(%q) = %q, want %q", tt.heading, got, tt.want)
+		}
+	}
+}
+
+func TestIsOrgaoHeading(t *testing.T) {
+	tests := []struct {
+		heading string
+		want    bool
+	}{
+		{"SECRETARIA DE ESTADO DA SAÚDE", true},
+		{"PREFEITURA MUNICIPAL DE PICOS - PI", true},
+		{"GOVERNO DO ESTADO DO PIAUÍ", true},
+		{"Secretaria de Estado da Saúde", false},
+		{"PORTARIA Nº 12/2025", false},
+		{"DIÁRIO OFICIAL", false},
+	}
+
+	for _, tt := range tests {
+		if got := isOrgaoHeading(tt.heading); got != tt.want {
+			t.Errorf("isOrgaoHeading(%q) = %v, want %v", tt.heading, got, tt.want)
+		}
+	}
+}
+
+func TestMunicipality(t *testing.T) {
+	tests := []struct {
+		orgao string
+		want  string
+	}{
+		{"PREFEITURA MUNICIPAL DE PICOS - PI", "PICOS"},
+		{"CÂMARA MUNICIPAL DE SÃO JOÃO DO PIAUÍ", "SÃO JOÃO DO PIAUÍ"},
+		{"SECRETARIA DE ESTADO DA SAÚDE", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Municipality(tt.orgao); got != tt.want {
+			t.Errorf("Municipality(%q) = %q, want %q", tt.orgao, got, tt.want)
+		}
+	}
+}
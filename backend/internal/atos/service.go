@@ -0,0 +1,91 @@
+package atos
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"radaroficial.app/internal/model"
+)
+
+type AtoService struct {
+	DB *pgxpool.Pool
+}
+
+func NewAtoService(db *pgxpool.Pool) *AtoService {
+	return &AtoService{DB: db}
+}
+
+// ReplaceForDiario replaces the acts stored for a diario, setting their IDs and DiarioID
+func (s *AtoService) ReplaceForDiario(ctx context.Context, diarioID int, atos []model.Ato) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM atos WHERE diario_id = $1`, diarioID); err != nil {
+		return fmt.Errorf("failed to delete previous atos: %w", err)
+	}
+
+	query := `
+		INSERT INTO atos (
+			diario_id, seq, orgao, act_type, number, year, title, content,
+			page_start, page_end, ocr, ocr_confidence, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW())
+		RETURNING id, created_at;
+	`
+
+	for i := range atos {
+		a := &atos[i]
+		a.DiarioID = diarioID
+
+		err := tx.QueryRow(ctx, query,
+			a.DiarioID, a.Seq, a.Orgao, a.ActType, a.Number, a.Year, a.Title, a.Content,
+			a.PageStart, a.PageEnd, a.OCR, a.OCRConfidence,
+		).Scan(&a.ID, &a.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert ato %d: %w", a.Seq, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ListByDiario returns the acts of a diario in publication order
+func (s *AtoService) ListByDiario(ctx context.Context, diarioID int) ([]model.Ato, error) {
+	query := `
+		SELECT
+			id, diario_id, seq, orgao, act_type, number, year, title, content,
+			page_start, page_end, ocr, ocr_confidence, created_at
+		FROM atos
+		WHERE diario_id = $1
+		ORDER BY seq ASC;
+	`
+
+	rows, err := s.DB.Query(ctx, query, diarioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var atos []model.Ato
+	for rows.Next() {
+		var a model.Ato
+		err := rows.Scan(
+			&a.ID, &a.DiarioID, &a.Seq, &a.Orgao, &a.ActType, &a.Number, &a.Year, &a.Title, &a.Content,
+			&a.PageStart, &a.PageEnd, &a.OCR, &a.OCRConfidence, &a.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		atos = append(atos, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return atos, nil
+}
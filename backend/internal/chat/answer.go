@@ -0,0 +1,154 @@
+package chat
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// Reasoning models may think aloud before answering, until the end of a cut reply
+	thinkingRegexp = regexp.MustCompile(`(?s)<think>.*?(?:</think>|$)`)
+	markerRegexp   = regexp.MustCompile(`\[(\d+)\]`)
+	blankRegexp    = regexp.MustCompile(`\n{3,}`)
+)
+
+// postProcess cleans the reply of the model: reasoning and markers citing sources that
+// were not sent are removed
+func postProcess(reply string, sources int) string {
+	reply = thinkingRegexp.ReplaceAllString(reply, "")
+
+	reply = markerRegexp.ReplaceAllStringFunc(reply, func(marker string) string {
+		n, _ := strconv.Atoi(marker[1 : len(marker)-1])
+		if n < 1 || n > sources {
+			return ""
+		}
+		return marker
+	})
+
+	reply = blankRegexp.ReplaceAllString(reply, "\n\n")
+	return strings.TrimSpace(reply)
+}
+
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// streamFilter applies postProcess to a reply as it is streamed. Text that may start a
+// reasoning block or a marker is held back until the next delta decides it.
+type streamFilter struct {
+	sources  int
+	pending  string // Text not decided yet
+	thinking bool   // Inside a reasoning block
+	started  bool   // Text was emitted, so blank space is no longer leading
+	newlines int    // Newlines held back, at most two are emitted
+}
+
+// write returns the text of a delta to stream, once filtered
+func (f *streamFilter) write(delta string) string {
+	var out strings.Builder
+	f.pending += delta
+
+	for f.pending != "" {
+		if f.thinking {
+			i := strings.Index(f.pending, thinkClose)
+			if i < 0 {
+				f.pending = partialSuffix(f.pending, thinkClose)
+				break
+			}
+			f.pending = f.pending[i+len(thinkClose):]
+			f.thinking = false
+			continue
+		}
+
+		i := strings.IndexAny(f.pending, "<[")
+		if i < 0 {
+			f.emit(&out, f.pending)
+			f.pending = ""
+			break
+		}
+
+		f.emit(&out, f.pending[:i])
+		rest := f.pending[i:]
+
+		if rest[0] == '<' {
+			switch {
+			case strings.HasPrefix(rest, thinkOpen):
+				f.thinking = true
+				f.pending = rest[len(thinkOpen):]
+			case strings.HasPrefix(thinkOpen, rest):
+				// Wait for the rest of the tag
+				f.pending = rest
+				return out.String()
+			default:
+				f.emit(&out, "<")
+				f.pending = rest[1:]
+			}
+			continue
+		}
+
+		end := 1
+		for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+			end++
+		}
+
+		switch {
+		case end == len(rest):
+			// Wait for the rest of the marker
+			f.pending = rest
+			return out.String()
+		case end > 1 && rest[end] == ']':
+			marker := rest[:end+1]
+			if n, _ := strconv.Atoi(marker[1:end]); n >= 1 && n <= f.sources {
+				f.emit(&out, marker)
+			}
+			f.pending = rest[end+1:]
+		default:
+			f.emit(&out, "[")
+			f.pending = rest[1:]
+		}
+	}
+
+	return out.String()
+}
+
+// flush returns the text held back at the end of the reply
+func (f *streamFilter) flush() string {
+	var out strings.Builder
+	if !f.thinking {
+		f.emit(&out, f.pending)
+	}
+	f.pending = ""
+	return out.String()
+}
+
+// emit writes text dropping leading blank space and collapsing runs of newlines, whose
+// trailing ones are held back as the reply may end with them. It works on bytes, as a
+// delta may end inside a multibyte character.
+func (f *streamFilter) emit(out *strings.Builder, text string) {
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '\n':
+			if f.started {
+				f.newlines++
+			}
+		case !f.started && (c == ' ' || c == '\t' || c == '\r'):
+		default:
+			out.WriteString(strings.Repeat("\n", min(f.newlines, 2)))
+			f.newlines = 0
+			out.WriteByte(c)
+			f.started = true
+		}
+	}
+}
+
+// partialSuffix returns the end of s that starts tag, if any
+func partialSuffix(s, tag string) string {
+	for i := max(len(s)-len(tag)+1, 0); i < len(s); i++ {
+		if strings.HasPrefix(tag, s[i:]) {
+			return s[i:]
+		}
+	}
+	return ""
+}
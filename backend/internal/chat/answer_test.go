@@ -0,0 +1,50 @@
+package chat
+
+import "testing"
+
+func TestPostProcess(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  string
+	}{
+		{"<think>fontes 1 e 2</think>\nA portaria nomeou João [1].", "A portaria nomeou João [1]."},
+		{"Foi exonerada [2] e nomeada [7].", "Foi exonerada [2] e nomeada ."},
+		{"Primeiro.\n\n\n\nSegundo [0].", "Primeiro.\n\nSegundo ."},
+		{"Resposta [1]\n<think>cortado", "Resposta [1]"},
+	}
+
+	for _, tt := range tests {
+		if got := postProcess(tt.reply, 3); got != tt.want {
+			t.Errorf("postProcess(%q) = %q, want %q", tt.reply, got, tt.want)
+		}
+	}
+}
+
+// Streamed text must read as the post-processed reply however the model splits it
+func TestStreamFilterMatchesPostProcess(t *testing.T) {
+	replies := []string{
+		"<think>fontes 1 e 2</think>\nA portaria nomeou João [1].",
+		"Foi exonerada [2] e nomeada [7], vide [a] e [12345].",
+		"  \nPrimeiro.\n\n\n\nSegundo [0] <b>negrito</b>.\n\n",
+		"Resposta [1]\n<think>cortado",
+		"Termina em [3",
+		"a < b e [ c ]",
+	}
+
+	for _, reply := range replies {
+		want := postProcess(reply, 3)
+
+		for size := 1; size <= len(reply); size++ {
+			f := &streamFilter{sources: 3}
+			var got string
+			for i := 0; i < len(reply); i += size {
+				got += f.write(reply[i:min(i+size, len(reply))])
+			}
+			got += f.flush()
+
+			if got != want {
+				t.Errorf("streamed %q in deltas of %d bytes = %q, want %q", reply, size, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package chat
+
+import (
+	"strconv"
+	"time"
+
+	"radaroficial.app/internal/search"
+)
+
+// Citation is a source of an answer, precise enough to be checked in the diario
+type Citation struct {
+	Number        int        `json:"number"` // Of the [n] markers in the answer
+	AtoID         int        `json:"atoId"`
+	DiarioID      int        `json:"diarioId"`
+	Institution   string     `json:"institution"`
+	Description   string     `json:"description"` // Edition of the diario
+	EditionNumber *int       `json:"editionNumber,omitempty"`
+	PublishedAt   *time.Time `json:"publishedAt,omitempty"`
+	Title         string     `json:"title"`
+	Page          int        `json:"page"`
+	URL           string     `json:"url"` // Stored PDF, anchored at the page
+}
+
+// citationsOf returns the sources cited by the [n] markers of an answer, in order of
+// first citation. An answer without markers, e.g. saying the sources do not answer the
+// question, has no citations.
+func citationsOf(answer string, sources []search.Hit) []Citation {
+	var numbers []int
+	seen := map[int]bool{}
+	for _, match := range markerRegexp.FindAllStringSubmatch(answer, -1) {
+		n, _ := strconv.Atoi(match[1])
+		if n >= 1 && n <= len(sources) && !seen[n] {
+			seen[n] = true
+			numbers = append(numbers, n)
+		}
+	}
+
+	citations := []Citation{}
+	for _, n := range numbers {
+		hit := sources[n-1]
+		citations = append(citations, Citation{
+			Number:        n,
+			AtoID:         hit.AtoID,
+			DiarioID:      hit.DiarioID,
+			Institution:   hit.Institution,
+			Description:   hit.Description,
+			EditionNumber: hit.EditionNumber,
+			PublishedAt:   hit.PublishedAt,
+			Title:         hit.Title,
+			Page:          hit.Page,
+			URL:           search.PageURL(hit.SourceURL, hit.Page),
+		})
+	}
+
+	return citations
+}
@@ -0,0 +1,145 @@
+package chat
+
+import (
+	"context"
+	"log"
+	"strings"
+	"unicode"
+
+	"radaroficial.app/internal/embedding"
+	"radaroficial.app/internal/llm"
+)
+
+const (
+	// historyTokenBudget bounds the previous turns sent with a question, older turns are
+	// summarized
+	historyTokenBudget = 2000
+	// maxTurnTokens bounds a single turn, long answers are cut
+	maxTurnTokens = 600
+	// maxSummaryInputTokens bounds the older turns sent to be summarized
+	maxSummaryInputTokens = 6000
+	maxSummaryTokens      = 300
+)
+
+const summaryPrompt = `Resuma a conversa a seguir entre um usuário e um assistente de diários oficiais em no máximo cinco frases. Mantenha datas, instituições, municípios, números de atos e de processos e nomes de pessoas citados. Responda apenas com o resumo.`
+
+// Turn is a previous message of a conversation
+type Turn struct {
+	Role string // llm.RoleUser or llm.RoleAssistant
+	Text string
+}
+
+// historyMessages returns the previous turns to send with a question: the latest ones
+// within historyTokenBudget, preceded by a summary of the older ones
+func (s *ChatService) historyMessages(ctx context.Context, history []Turn) []llm.Message {
+	used := 0
+	start := len(history)
+	for start > 0 {
+		tokens := embedding.CountTokens(embedding.TruncateTokens(history[start-1].Text, maxTurnTokens))
+		if used+tokens > historyTokenBudget {
+			break
+		}
+		used += tokens
+		start--
+	}
+
+	var messages []llm.Message
+
+	if start > 0 {
+		if summary := s.summarize(ctx, history[:start]); summary != "" {
+			messages = append(messages, llm.Message{
+				Role:    llm.RoleSystem,
+				Content: "Resumo do início da conversa:\n" + summary,
+			})
+		}
+	}
+
+	for _, turn := range history[start:] {
+		messages = append(messages, llm.Message{
+			Role:    turn.Role,
+			Content: embedding.TruncateTokens(turn.Text, maxTurnTokens),
+		})
+	}
+
+	return messages
+}
+
+// summarize asks the model for a summary of turns, keeping the latest when they exceed
+// maxSummaryInputTokens. A failure only loses the older turns.
+func (s *ChatService) summarize(ctx context.Context, turns []Turn) string {
+	var lines []string
+	used := 0
+	for i := len(turns) - 1; i >= 0; i-- {
+		line := turnLabel(turns[i].Role) + ": " + embedding.TruncateTokens(turns[i].Text, maxTurnTokens)
+		used += embedding.CountTokens(line)
+		if used > maxSummaryInputTokens {
+			break
+		}
+		lines = append([]string{line}, lines...)
+	}
+
+	reply, err := s.LLM.Complete(ctx, llm.Request{
+		Messages: []llm.Message{
+			{Role: llm.RoleSystem, Content: summaryPrompt},
+			{Role: llm.RoleUser, Content: strings.Join(lines, "\n\n")},
+		},
+		MaxTokens: maxSummaryTokens,
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to summarize %d older turn(s): %v", len(turns), err)
+		return ""
+	}
+
+	log.Printf("🧾 Summarized %d older turn(s)", len(turns))
+	return strings.TrimSpace(postProcess(reply.Content, 0))
+}
+
+func turnLabel(role string) string {
+	if role == llm.RoleAssistant {
+		return "Assistente"
+	}
+	return "Usuário"
+}
+
+// followUpText completes a question that depends on the previous ones, such as
+// "e a de ontem?", with the last question of the user, so retrieval knows what it is about
+func followUpText(question string, history []Turn) string {
+	if !isFollowUp(question) {
+		return question
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == llm.RoleUser && strings.TrimSpace(history[i].Text) != "" {
+			return history[i].Text + "\n" + question
+		}
+	}
+
+	return question
+}
+
+// followUpWords refer to something said before
+var followUpWords = toSet(`
+	disso desse dessa desses dessas deste desta nisso nesse nessa dele dela deles delas
+	mesmo mesma mesmos mesmas anterior anteriores acima
+`)
+
+// isFollowUp reports whether a question starts as a continuation, refers to something
+// said before or has no word to search alone
+func isFollowUp(question string) bool {
+	lower := strings.ToLower(strings.TrimSpace(question))
+	for _, prefix := range []string{"e ", "mas ", "também", "tambem", "outra", "outro"} {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+
+	for _, word := range strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		if followUpWords[word] {
+			return true
+		}
+	}
+
+	return RewriteQuery(question) == ""
+}
@@ -0,0 +1,76 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"radaroficial.app/internal/llm"
+	"radaroficial.app/internal/search"
+)
+
+// maxSourceContentLength bounds how much of each ato is sent to the model
+const maxSourceContentLength = 1500
+
+// SystemPrompt frames the model as the assistant of the diarios oficiais. The sources
+// retrieved for each question are sent in the user message.
+const SystemPrompt = `You are a helpful assistant trained to search and summarize content from **official publications from the state of Piauí, Brazil**, including:
+
+- The **Diário Oficial do Estado do Piauí (DOE-PI)**
+- Municipal official journals (e.g., Teresina, Parnaíba, Floriano, Picos)
+- Public entities such as IFPI (Instituto Federal do Piauí), secretarias estaduais, câmaras municipais, and autarquias
+
+Your goal is to help users find information about **recent publications** in these Diários, including laws, job postings, appointments, tenders, notices, and other government activities.
+
+Answer in Brazilian Portuguese, using only the numbered sources sent with the question. Cite the sources an answer relies on by their number, such as [1] or [2][3]. When the sources do not answer the question, say so plainly instead of guessing.`
+
+// buildMessages assembles the conversation sent to the model: the system prompt, the
+// previous turns, then the question preceded by the sources numbered from 1
+func buildMessages(question string, history []llm.Message, sources []search.Hit, filter search.Filter) []llm.Message {
+	var b strings.Builder
+
+	if len(sources) == 0 {
+		b.WriteString("Nenhum ato publicado foi encontrado para a pergunta")
+		if !filter.IsZero() {
+			fmt.Fprintf(&b, " (%s)", filter)
+		}
+		b.WriteString(".\n")
+	} else {
+		b.WriteString("Fontes:\n")
+	}
+
+	for i, source := range sources {
+		fmt.Fprintf(&b, "\n[%d] %s\n%s\n", i+1, sourceHeader(source), truncate(source.Content, maxSourceContentLength))
+	}
+
+	b.WriteString("\n-----\nPergunta: ")
+	b.WriteString(question)
+
+	messages := []llm.Message{{Role: llm.RoleSystem, Content: SystemPrompt}}
+	messages = append(messages, history...)
+	return append(messages, llm.Message{Role: llm.RoleUser, Content: b.String()})
+}
+
+// sourceHeader describes where an ato was published
+func sourceHeader(hit search.Hit) string {
+	parts := []string{hit.Title}
+	if hit.Institution != "" {
+		parts = append(parts, hit.Institution)
+	}
+	if hit.Description != "" {
+		parts = append(parts, hit.Description)
+	}
+	if hit.PublishedAt != nil {
+		parts = append(parts, "publicado em "+hit.PublishedAt.Format("02/01/2006"))
+	}
+	if hit.Page > 0 {
+		parts = append(parts, fmt.Sprintf("página %d", hit.Page))
+	}
+	return strings.Join(parts, " — ")
+}
+
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n]) + "..."
+	}
+	return s
+}
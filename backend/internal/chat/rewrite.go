@@ -0,0 +1,71 @@
+package chat
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// rewriteStopwords are words of questions that do not help find the atos: Portuguese
+// stopwords, requests to the assistant and references to the diarios themselves
+var rewriteStopwords = toSet(`
+	a à ao aos as às o os um uma uns umas de da das do dos em na nas no nos num numa
+	por pela pelas pelo pelos para pra com sem sob sobre entre até após e ou mas que se
+	é foi foram são ser será sido seja sejam está estão há tem têm teve houve fez qual quais
+	quem quando onde
+	como quanto quantos quantas porque isso isto este esta estes estas esse essa esses
+	essas aquele aquela me mim meu minha você vocês eu nós ele ela eles elas lhe
+	algum alguma alguns algumas todo toda todos todas mais menos muito já ainda também
+	poderia poderiam pode podem gostaria queria quero saber favor detalhar detalhe
+	detalhes explique explicar informe informar mostre mostrar liste listar diga dizer
+	fale falar encontre encontrar procure procurar busque buscar existe existem teve
+	diário diario diários diarios oficial oficiais publicado publicada publicados
+	publicadas publicação publicações edição hoje ontem
+	janeiro fevereiro março marco abril maio junho julho agosto setembro outubro
+	novembro dezembro
+`)
+
+// identifierRegexp matches tokens that look like numbers of processes, atos or documents
+var identifierRegexp = regexp.MustCompile(`\d[\d./-]*\d`)
+
+// RewriteQuery turns a question into a keyword query for search.ParseQuery: the words
+// that may appear in the atos, any of which can match, and identifiers as phrases
+func RewriteQuery(question string) string {
+	var terms []string
+	seen := map[string]bool{}
+
+	for _, token := range strings.Fields(question) {
+		token = strings.TrimFunc(token, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+
+		lower := strings.ToLower(token)
+		if lower == "" || seen[lower] || rewriteStopwords[lower] {
+			continue
+		}
+		seen[lower] = true
+
+		switch {
+		case identifierRegexp.MatchString(token) && strings.ContainsAny(token, "./-"):
+			terms = append(terms, `"`+token+`"`)
+		case len([]rune(token)) < 3 && !isDigits(token):
+			// Initials and particles match too many atos
+		default:
+			terms = append(terms, token)
+		}
+	}
+
+	return strings.Join(terms, " OR ")
+}
+
+func isDigits(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) }) == -1
+}
+
+func toSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
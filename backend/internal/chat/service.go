@@ -1,7 +1,242 @@
 package chat
 
-type ChatService struct{}
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
 
-func NewChatService() *ChatService {
-	return &ChatService{}
+	"github.com/jackc/pgx/v5/pgxpool"
+	"radaroficial.app/internal/identifiers"
+	"radaroficial.app/internal/institutions"
+	"radaroficial.app/internal/llm"
+	"radaroficial.app/internal/model"
+	"radaroficial.app/internal/search"
+)
+
+const (
+	// maxSources bounds how many atos are sent to the model with a question
+	maxSources = 8
+	// maxIdentifierSources bounds the sources citing identifiers of the question, which
+	// come first as they match exactly
+	maxIdentifierSources = 4
+)
+
+// ErrNoModel is returned when no model is configured, see llm.NewLLMClientFromEnv
+var ErrNoModel = errors.New("no model configured")
+
+// Question is a question to the assistant, restricted to the filter selected by the
+// user, at least to a state, asked after the previous turns of the conversation
+type Question struct {
+	Text    string
+	Filter  search.Filter
+	History []Turn // Oldest first
+}
+
+// Answer is the reply of the assistant with the atos it was given, numbered from 1 in
+// the [n] markers of Text, and those it cites
+type Answer struct {
+	Text      string
+	Sources   []search.Hit
+	Citations []Citation
+}
+
+// The services ChatService retrieves from, implemented by
+// institutions.InstitutionService, identifiers.IdentifierService and search.HybridService
+type (
+	InstitutionLister interface {
+		ListActive(ctx context.Context, state string) ([]model.Institution, error)
+	}
+	IdentifierLookup interface {
+		LookupText(ctx context.Context, text string) ([]identifiers.Match, error)
+	}
+	AtoSearcher interface {
+		SearchQuestion(ctx context.Context, question, keywords string, filter search.Filter, limit, offset int) ([]search.Hit, bool, error)
+	}
+)
+
+// ChatService answers questions about the diarios with our own retrieval: the question
+// is rewritten into a keyword query, atos are retrieved from the index with the filter
+// selected and the one the question implies, and the model answers from them
+type ChatService struct {
+	InstitutionService InstitutionLister
+	IdentifierService  IdentifierLookup
+	Search             AtoSearcher
+	LLM                llm.LLMClient
+}
+
+// NewChatService creates a new ChatService retrieving atos by keyword from db and by
+// meaning from semantic, which is optional, and answering with the model configured in
+// the environment
+func NewChatService(db *pgxpool.Pool, semantic search.Retriever) *ChatService {
+	client, err := llm.NewLLMClientFromEnv()
+	if err != nil {
+		log.Printf("⚠️ Failed to configure the chat model: %v", err)
+	}
+
+	return &ChatService{
+		InstitutionService: institutions.NewInstitutionService(db),
+		IdentifierService:  identifiers.NewIdentifierService(db),
+		Search:             search.NewHybridService(db, semantic),
+		LLM:                client,
+	}
+}
+
+// Answer retrieves the atos relevant to a question and asks the model to answer from them
+func (s *ChatService) Answer(ctx context.Context, q Question) (*Answer, error) {
+	if s.LLM == nil {
+		return nil, ErrNoModel
+	}
+
+	req, sources := s.prepare(ctx, q)
+
+	reply, err := s.LLM.Complete(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete answer: %w", err)
+	}
+
+	return answerOf(reply.Content, sources), nil
+}
+
+// AnswerStream answers as Answer, passing the reply to onDelta as the model generates
+// it. Streamed content is post-processed as it arrives, as the text of the returned
+// Answer. The stream stops when ctx is canceled or onDelta fails.
+func (s *ChatService) AnswerStream(ctx context.Context, q Question, onDelta func(llm.Delta) error) (*Answer, error) {
+	if s.LLM == nil {
+		return nil, ErrNoModel
+	}
+
+	req, sources := s.prepare(ctx, q)
+
+	filter := &streamFilter{sources: len(sources)}
+	reply, err := s.LLM.Stream(ctx, req, func(delta llm.Delta) error {
+		if delta.ToolCall != nil {
+			return onDelta(delta)
+		}
+		if text := filter.write(delta.Content); text != "" {
+			return onDelta(llm.Delta{Content: text})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to stream answer: %w", err)
+	}
+
+	if text := filter.flush(); text != "" {
+		if err := onDelta(llm.Delta{Content: text}); err != nil {
+			return nil, err
+		}
+	}
+
+	return answerOf(reply.Content, sources), nil
+}
+
+func answerOf(reply string, sources []search.Hit) *Answer {
+	text := postProcess(reply, len(sources))
+	return &Answer{Text: text, Sources: sources, Citations: citationsOf(text, sources)}
+}
+
+// prepare retrieves the sources of a question and assembles the request to the model
+func (s *ChatService) prepare(ctx context.Context, q Question) (llm.Request, []search.Hit) {
+	// Follow-ups are retrieved with the question they follow
+	text := followUpText(q.Text, q.History)
+
+	filter := s.questionFilter(ctx, q, text)
+	sources := s.retrieve(ctx, text, filter, q.Filter)
+	history := s.historyMessages(ctx, q.History)
+
+	return llm.Request{Messages: buildMessages(q.Text, history, sources, filter)}, sources
+}
+
+// questionFilter completes the filter selected by the user with the period,
+// institutions and municipalities the question mentions, then those of the question it
+// follows, in text
+func (s *ChatService) questionFilter(ctx context.Context, q Question, text string) search.Filter {
+	list, err := s.InstitutionService.ListActive(ctx, q.Filter.State)
+	if err != nil {
+		log.Printf("⚠️ Failed to list institutions: %v", err)
+		return q.Filter
+	}
+
+	now := time.Now()
+	filter := q.Filter.Merge(search.ParseQuestion(q.Text, list, now))
+	if text != q.Text {
+		filter = filter.Merge(search.ParseQuestion(text, list, now))
+	}
+	return filter
+}
+
+// retrieve returns the atos citing identifiers of the question, then those best matching
+// it under filter. When the filter the question implies matches nothing, the selected
+// one is tried, as the question may have been misread.
+func (s *ChatService) retrieve(ctx context.Context, question string, filter, selected search.Filter) []search.Hit {
+	var sources []search.Hit
+	seen := map[int]bool{}
+
+	add := func(hit search.Hit) {
+		if len(sources) < maxSources && !seen[hit.AtoID] {
+			seen[hit.AtoID] = true
+			sources = append(sources, hit)
+		}
+	}
+
+	matches, err := s.IdentifierService.LookupText(ctx, question)
+	if err != nil {
+		log.Printf("⚠️ Failed to look up identifiers in question: %v", err)
+	}
+	for _, m := range matches {
+		if len(sources) == maxIdentifierSources {
+			break
+		}
+		if selected.State == "" || m.State == selected.State {
+			add(hitOfMatch(m))
+		}
+	}
+
+	// The rewrite only serves the full-text ranking, the semantic one embeds the question
+	query := RewriteQuery(question)
+	if query == "" {
+		query = question
+	}
+
+	hits, _, err := s.Search.SearchQuestion(ctx, question, query, filter, maxSources, 0)
+	if err == nil && len(hits) == 0 && filter.String() != selected.String() {
+		log.Printf("🔎 No ato matching %s, retrying with %s", filter, selected)
+		hits, _, err = s.Search.SearchQuestion(ctx, question, query, selected, maxSources, 0)
+	}
+	if err != nil && !errors.Is(err, search.ErrEmptyQuery) {
+		log.Printf("⚠️ Failed to search atos matching %s: %v", filter, err)
+	}
+
+	for _, hit := range hits {
+		add(hit)
+	}
+
+	log.Printf("🔎 Retrieved %d ato(s) for %q (%s)", len(sources), query, filter)
+	return sources
+}
+
+// hitOfMatch describes an ato found by an identifier as a search hit
+func hitOfMatch(m identifiers.Match) search.Hit {
+	hit := search.Hit{
+		AtoID:         m.Identifier.AtoID,
+		DiarioID:      m.Identifier.DiarioID,
+		InstitutionID: m.InstitutionID,
+		Institution:   m.Institution,
+		State:         m.State,
+		EditionNumber: m.EditionNumber,
+		PublishedAt:   m.PublishedAt,
+		SourceURL:     m.SourceURL,
+		PageURL:       search.PageURL(m.SourceURL, m.PageStart),
+		ActType:       m.ActType,
+		Title:         m.AtoTitle,
+		Content:       m.Content,
+		Page:          m.PageStart,
+		PageEnd:       m.PageEnd,
+	}
+	if m.DiarioDescription != nil {
+		hit.Description = *m.DiarioDescription
+	}
+	return hit
 }
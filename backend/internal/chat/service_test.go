@@ -0,0 +1,125 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"radaroficial.app/internal/identifiers"
+	"radaroficial.app/internal/llm"
+	"radaroficial.app/internal/model"
+	"radaroficial.app/internal/search"
+)
+
+type fakeInstitutions struct{}
+
+func (fakeInstitutions) ListActive(ctx context.Context, state string) ([]model.Institution, error) {
+	return nil, nil
+}
+
+type fakeIdentifiers struct{}
+
+func (fakeIdentifiers) LookupText(ctx context.Context, text string) ([]identifiers.Match, error) {
+	return nil, nil
+}
+
+type fakeSearch struct{ hits []search.Hit }
+
+func (s fakeSearch) SearchQuestion(ctx context.Context, question, keywords string, filter search.Filter, limit, offset int) ([]search.Hit, bool, error) {
+	return s.hits, false, nil
+}
+
+// newOfflineChat returns a ChatService answering with the fake LLM server replying as
+// the script of cmd/fakellm, from sources that mention both nomeações and licitações
+func newOfflineChat(t *testing.T) (*ChatService, *llm.FakeServer) {
+	t.Helper()
+
+	script, err := llm.LoadScript("../../scripts/fake_llm_script.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake, err := llm.NewFakeServer(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	hits := []search.Hit{
+		{AtoID: 1, DiarioID: 10, Institution: "Prefeitura de Teresina", Title: "Portaria nº 12", Content: "Nomeação de Maria para o cargo de assessora.", Page: 3},
+		{AtoID: 2, DiarioID: 10, Institution: "Prefeitura de Teresina", Title: "Aviso de licitação", Content: "Pregão eletrônico para aquisição de merenda.", Page: 5},
+	}
+
+	return &ChatService{
+		InstitutionService: fakeInstitutions{},
+		IdentifierService:  fakeIdentifiers{},
+		Search:             fakeSearch{hits: hits},
+		LLM:                llm.NewOpenAIClient(llm.OpenAIConfig{BaseURL: server.URL, Model: "fake"}),
+	}, fake
+}
+
+func TestAnswerOffline(t *testing.T) {
+	chat, fake := newOfflineChat(t)
+
+	answer, err := chat.Answer(context.Background(), Question{Text: "Quais nomeações saíram?", Filter: search.Filter{State: "PI"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(answer.Text, "Foram publicadas nomeações") {
+		t.Errorf("answer = %q, want the scripted reply about nomeações", answer.Text)
+	}
+	if len(answer.Citations) != 2 || answer.Citations[0].AtoID != 1 || answer.Citations[1].AtoID != 2 {
+		t.Errorf("citations = %+v, want atos 1 and 2", answer.Citations)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests to the model, want 1", len(requests))
+	}
+	var body struct {
+		Messages []struct{ Content string } `json:"messages"`
+	}
+	if err := json.Unmarshal(requests[0].Body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if prompt := body.Messages[len(body.Messages)-1].Content; !strings.Contains(prompt, "[2] Aviso de licitação") {
+		t.Errorf("prompt does not list the sources: %q", prompt)
+	}
+}
+
+// The sources mention nomeações, the question only licitações
+func TestAnswerOfflineMatchesQuestion(t *testing.T) {
+	chat, _ := newOfflineChat(t)
+
+	answer, err := chat.Answer(context.Background(), Question{Text: "Houve alguma licitação?", Filter: search.Filter{State: "PI"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "Encontrei um aviso de licitação publicado recentemente [1]."; answer.Text != want {
+		t.Errorf("answer = %q, want %q", answer.Text, want)
+	}
+}
+
+func TestAnswerStreamOffline(t *testing.T) {
+	chat, _ := newOfflineChat(t)
+
+	var streamed strings.Builder
+	answer, err := chat.AnswerStream(context.Background(), Question{Text: "Quais nomeações saíram?", Filter: search.Filter{State: "PI"}}, func(delta llm.Delta) error {
+		streamed.WriteString(delta.Content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if streamed.String() != answer.Text {
+		t.Errorf("streamed %q, answered %q", streamed.String(), answer.Text)
+	}
+	if len(answer.Citations) != 2 {
+		t.Errorf("citations = %+v, want 2", answer.Citations)
+	}
+}
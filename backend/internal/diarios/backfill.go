@@ -0,0 +1,175 @@
+package diarios
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"radaroficial.app/internal/model"
+)
+
+type BackfillService struct {
+	DB *pgxpool.Pool
+}
+
+func NewBackfillService(db *pgxpool.Pool) *BackfillService {
+	return &BackfillService{DB: db}
+}
+
+// GetOrCreate returns the backfill of an institution over a date range, creating it
+// when it does not exist yet. An existing backfill keeps its progress.
+func (s *BackfillService) GetOrCreate(ctx context.Context, institutionID int, start, end time.Time) (*model.Backfill, error) {
+	query := `
+		INSERT INTO backfills (
+			institution_id,
+			start_date,
+			end_date,
+			next_date,
+			created_at,
+			updated_at
+		)
+		VALUES ($1, $2, $3, $2, NOW(), NOW())
+		ON CONFLICT (institution_id, start_date, end_date) DO UPDATE
+			SET updated_at = NOW()
+		RETURNING id, institution_id, start_date, end_date, next_date,
+			enqueued_days, completed_at, created_at, updated_at;
+	`
+
+	b := &model.Backfill{}
+	err := s.DB.QueryRow(ctx, query, institutionID, start, end).Scan(
+		&b.ID, &b.InstitutionID, &b.StartDate, &b.EndDate, &b.NextDate,
+		&b.EnqueuedDays, &b.CompletedAt, &b.CreatedAt, &b.UpdatedAt,
+	)
+
+	return b, err
+}
+
+// GetByID returns the backfill with the given ID, or nil when there is none
+func (s *BackfillService) GetByID(ctx context.Context, id int) (*model.Backfill, error) {
+	query := `
+		SELECT id, institution_id, start_date, end_date, next_date,
+			enqueued_days, completed_at, created_at, updated_at
+		FROM backfills
+		WHERE id = $1;
+	`
+
+	b := &model.Backfill{}
+	err := s.DB.QueryRow(ctx, query, id).Scan(
+		&b.ID, &b.InstitutionID, &b.StartDate, &b.EndDate, &b.NextDate,
+		&b.EnqueuedDays, &b.CompletedAt, &b.CreatedAt, &b.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return b, err
+}
+
+// Advance records that day has been enqueued, every day before it already being. It
+// runs inside the transaction that enqueues the day so progress and jobs are committed
+// together.
+func (s *BackfillService) Advance(ctx context.Context, tx pgx.Tx, id int, day time.Time) error {
+	query := `
+		UPDATE backfills
+		SET
+			next_date = $2::date + 1,
+			enqueued_days = enqueued_days + 1,
+			updated_at = NOW()
+		WHERE id = $1;
+	`
+
+	if _, err := tx.Exec(ctx, query, id, day); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, `INSERT INTO backfill_days (backfill_id, day) VALUES ($1, $2) ON CONFLICT DO NOTHING;`, id, day)
+	return err
+}
+
+// ListUnfetchedDays returns the enqueued days of a backfill whose fetch has not
+// succeeded yet, oldest first
+func (s *BackfillService) ListUnfetchedDays(ctx context.Context, id int) ([]time.Time, error) {
+	query := `
+		SELECT day FROM backfill_days
+		WHERE backfill_id = $1 AND fetched_at IS NULL
+		ORDER BY day ASC;
+	`
+
+	rows, err := s.DB.Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []time.Time
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+
+	return days, rows.Err()
+}
+
+// MarkDayFetched records that the fetch of a day of a backfill succeeded, completing the
+// backfill once every day has been enqueued and fetched
+func (s *BackfillService) MarkDayFetched(ctx context.Context, id int, day time.Time) error {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Serializes the last days fetched concurrently, so one of them sees the others
+	if _, err := tx.Exec(ctx, `SELECT id FROM backfills WHERE id = $1 FOR UPDATE;`, id); err != nil {
+		return err
+	}
+
+	fetched := `
+		UPDATE backfill_days
+		SET fetched_at = NOW()
+		WHERE backfill_id = $1 AND day = $2 AND fetched_at IS NULL;
+	`
+
+	if _, err := tx.Exec(ctx, fetched, id, day); err != nil {
+		return err
+	}
+
+	completed := `
+		UPDATE backfills
+		SET
+			completed_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1 AND completed_at IS NULL AND next_date > end_date
+			AND NOT EXISTS (
+				SELECT 1 FROM backfill_days
+				WHERE backfill_id = $1 AND fetched_at IS NULL
+			);
+	`
+
+	if _, err := tx.Exec(ctx, completed, id); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// MarkCompleted records that every edition of a backfill walking back by edition number
+// has been registered
+func (s *BackfillService) MarkCompleted(ctx context.Context, id int) error {
+	query := `
+		UPDATE backfills
+		SET
+			completed_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1 AND completed_at IS NULL;
+	`
+
+	_, err := s.DB.Exec(ctx, query, id)
+	return err
+}
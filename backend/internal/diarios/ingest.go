@@ -0,0 +1,274 @@
+package diarios
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"radaroficial.app/internal/atos"
+	"radaroficial.app/internal/extract"
+	"radaroficial.app/internal/identifiers"
+	"radaroficial.app/internal/model"
+	"radaroficial.app/internal/storage"
+	"radaroficial.app/internal/vectorstore"
+)
+
+// ErrDuplicate is returned by Download when the PDF is already stored for another diario
+var ErrDuplicate = errors.New("duplicate PDF")
+
+// Ingester runs the stages of the ingestion pipeline: each edition discovered from a
+// DiarioSource is registered, downloaded, extracted into atos, indexed and finalized.
+// Every stage is idempotent, so the job running it can be retried on its own.
+type Ingester struct {
+	Service           *DiarioService
+	AtoService        *atos.AtoService
+	IdentifierService *identifiers.IdentifierService
+	Store             storage.BlobStore
+	Converter         extract.Converter
+	VectorStore       vectorstore.VectorStore
+}
+
+// NewIngester creates a new Ingester
+func NewIngester(service *DiarioService, atoService *atos.AtoService, identifierService *identifiers.IdentifierService, store storage.BlobStore, converter extract.Converter, vectorStore vectorstore.VectorStore) *Ingester {
+	return &Ingester{
+		Service:           service,
+		AtoService:        atoService,
+		IdentifierService: identifierService,
+		Store:             store,
+		Converter:         converter,
+		VectorStore:       vectorStore,
+	}
+}
+
+// Register records a discovered edition within tx. It returns the diario whose PDF must
+// be downloaded: a new one, or an existing one republished with a newer modification
+// time. It returns nil when there is nothing to download.
+func (i *Ingester) Register(ctx context.Context, tx pgx.Tx, institution *model.Institution, edition Edition) (*model.Diario, error) {
+	existing, err := i.Service.GetByDescription(ctx, institution.ID, edition.Description)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if diario exists: %w", err)
+	}
+
+	if existing != nil {
+		if isRepublished(existing, edition) {
+			log.Printf("🔁 Diario %s was modified at %s", edition.Description, edition.LastModifiedAt.Format(time.RFC3339))
+			return existing, nil
+		}
+
+		log.Printf("✅ Skipping already registered diario: %s", edition.Description)
+		return nil, nil
+	}
+
+	lastModifiedAt := edition.LastModifiedAt
+	if lastModifiedAt == nil {
+		now := time.Now()
+		lastModifiedAt = &now
+	}
+
+	publishedAt := edition.PublishedAt
+	description := edition.Description
+	downloadURL := edition.DownloadURL
+
+	diario := &model.Diario{
+		InstitutionID:  institution.ID,
+		Description:    &description,
+		PublishedAt:    &publishedAt,
+		LastModifiedAt: lastModifiedAt,
+		DownloadURL:    &downloadURL,
+		Status:         StatusDiscovered,
+	}
+
+	if edition.Number > 0 {
+		editionNumber := edition.Number
+		diario.EditionNumber = &editionNumber
+	}
+
+	if err := i.Service.InsertTx(ctx, tx, diario); err != nil {
+		return nil, fmt.Errorf("failed to insert diário %s: %w", description, err)
+	}
+
+	if diario.ID == 0 {
+		log.Printf("✅ Skipping diário registered concurrently: %s", description)
+		return nil, nil
+	}
+
+	log.Printf("🆕 Registered diário %s (ID: %d)", description, diario.ID)
+	return diario, nil
+}
+
+// isRepublished reports whether the source lists a newer modification time for an
+// edition than the one stored, as happens when a PDF is replaced after an errata
+func isRepublished(existing *model.Diario, edition Edition) bool {
+	if edition.LastModifiedAt == nil || existing.LastModifiedAt == nil {
+		return false
+	}
+
+	return edition.LastModifiedAt.After(*existing.LastModifiedAt)
+}
+
+// Download fetches the PDF of a diario from its source and stores it under its content
+// hash. It returns false when the PDF of an already ingested diario did not change, and
+// ErrDuplicate when the same PDF is stored for another diario of the institution.
+// A changed PDF is only set on diario, the caller records it with DiarioService.RecordPDF
+// in the transaction that moves the diario on, so a version is never left unprocessed.
+func (i *Ingester) Download(ctx context.Context, src DiarioSource, diario *model.Diario, downloadURL string, lastModifiedAt *time.Time) (bool, error) {
+	edition := editionOf(diario, downloadURL, lastModifiedAt)
+
+	log.Printf("📥 Downloading diario %s URL %s", edition.Description, downloadURL)
+
+	pdfContent, err := src.Download(ctx, edition)
+	if err != nil {
+		return false, fmt.Errorf("failed to download PDF from %s: %w", downloadURL, err)
+	}
+
+	sha256, _ := storage.Checksum(pdfContent)
+
+	if diario.SHA256 != nil && *diario.SHA256 == sha256 {
+		if diario.Status == StatusDownloaded {
+			// Already stored by a previous attempt of this job
+			return true, nil
+		}
+
+		log.Printf("✅ PDF of %s is unchanged", edition.Description)
+		if lastModifiedAt != nil {
+			if err := i.Service.TouchLastModified(ctx, diario.ID, *lastModifiedAt); err != nil {
+				return false, fmt.Errorf("failed to update last modification of diário %d: %w", diario.ID, err)
+			}
+		}
+		return false, nil
+	}
+
+	// Identical files re-published under another description, or as a new version of
+	// another diario, are stored and indexed once
+	duplicateOf, err := i.Service.FindBySHA256(ctx, diario.InstitutionID, diario.ID, sha256)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for duplicate PDF: %w", err)
+	}
+
+	if duplicateOf != nil {
+		log.Printf("✅ %s has the same PDF as already stored diario %s", edition.Description, *duplicateOf)
+		return false, ErrDuplicate
+	}
+
+	// Store the PDF under its content hash, e.g. "sha256/ab/ab12....pdf"
+	objectKey, sha256, size, err := storage.PutContent(ctx, i.Store, pdfContent, ".pdf", "application/pdf")
+	if err != nil {
+		return false, fmt.Errorf("failed to upload PDF to storage: %w", err)
+	}
+
+	diario.SourceURL = i.Store.URL(objectKey)
+	diario.SHA256 = &sha256
+	diario.SizeBytes = &size
+	diario.ObjectKey = &objectKey
+	diario.DownloadURL = &downloadURL
+	if lastModifiedAt != nil {
+		diario.LastModifiedAt = lastModifiedAt
+	}
+
+	log.Printf("✅ Successfully stored %s as %s (%d bytes)", edition.Description, objectKey, size)
+	return true, nil
+}
+
+// editionOf rebuilds the Edition a diario was registered from
+func editionOf(diario *model.Diario, downloadURL string, lastModifiedAt *time.Time) Edition {
+	edition := Edition{
+		DownloadURL:    downloadURL,
+		LastModifiedAt: lastModifiedAt,
+		Filename:       path.Base(downloadURL),
+	}
+
+	if diario.Description != nil {
+		edition.Description = *diario.Description
+	}
+	if diario.PublishedAt != nil {
+		edition.PublishedAt = *diario.PublishedAt
+	}
+	if diario.EditionNumber != nil {
+		edition.Number = *diario.EditionNumber
+	}
+
+	return edition
+}
+
+// Extract converts the stored PDF of a diario and segments it into atos, which are
+// stored with the identifiers they cite, replacing those of any previous attempt
+func (i *Ingester) Extract(ctx context.Context, diario *model.Diario) (int, error) {
+	pdfContent, err := i.LoadPDF(ctx, diario)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load PDF: %w", err)
+	}
+
+	pages, err := i.Converter.Convert(ctx, pdfContent)
+	if err != nil {
+		return 0, fmt.Errorf("failed to split PDF: %w", err)
+	}
+
+	pageCount := 0
+	for _, page := range pages {
+		pageCount = max(pageCount, page.Number)
+	}
+	if err := i.Service.RecordPageCount(ctx, diario.ID, pageCount); err != nil {
+		return 0, fmt.Errorf("failed to record page count: %w", err)
+	}
+	diario.PageCount = &pageCount
+
+	segmented := atos.Segment(pages)
+
+	if err := i.AtoService.ReplaceForDiario(ctx, diario.ID, segmented); err != nil {
+		return 0, fmt.Errorf("failed to store atos: %w", err)
+	}
+
+	found, err := i.IdentifierService.ExtractForAtos(ctx, segmented)
+	if err != nil {
+		return 0, fmt.Errorf("failed to extract identifiers: %w", err)
+	}
+
+	log.Printf("🧩 Extracted %d ato(s) and %d identifier(s) of diário %d", len(segmented), found, diario.ID)
+	return len(segmented), nil
+}
+
+// Index replaces the atos indexed in the vector store for a diario with its stored atos.
+// Running it again for the same diario leaves a single object per ato.
+func (i *Ingester) Index(ctx context.Context, diario *model.Diario, institution *model.Institution) (int, error) {
+	stored, err := i.AtoService.ListByDiario(ctx, diario.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list atos: %w", err)
+	}
+
+	// Atos are upserted before those of a previous version, which may have had more, are
+	// removed, so the diario stays searchable if indexing fails midway
+	if err := i.VectorStore.UpsertAtos(ctx, stored, diario, institution); err != nil {
+		return 0, fmt.Errorf("failed to index atos in vector store: %w", err)
+	}
+
+	if _, err := i.VectorStore.DeleteStale(ctx, diario, stored); err != nil {
+		return 0, fmt.Errorf("failed to remove previous atos from vector store: %w", err)
+	}
+
+	log.Printf("🧩 Indexed %d ato(s) of diário %d", len(stored), diario.ID)
+	return len(stored), nil
+}
+
+// LoadPDF reads the stored PDF of a diario back, verifying it against the checksum and
+// size recorded at download time
+func (i *Ingester) LoadPDF(ctx context.Context, diario *model.Diario) ([]byte, error) {
+	if diario.ObjectKey == nil {
+		return nil, fmt.Errorf("diario %d has no stored object", diario.ID)
+	}
+
+	sha256 := ""
+	if diario.SHA256 != nil {
+		sha256 = *diario.SHA256
+	}
+
+	size := int64(-1)
+	if diario.SizeBytes != nil {
+		size = *diario.SizeBytes
+	}
+
+	return storage.ReadVerified(ctx, i.Store, *diario.ObjectKey, sha256, size)
+}
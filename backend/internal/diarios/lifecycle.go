@@ -0,0 +1,92 @@
+package diarios
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"radaroficial.app/internal/model"
+)
+
+// Lifecycle statuses of a diario, stored in the status column of diarios. Each one
+// records the last stage of the ingestion pipeline the diario completed.
+const (
+	StatusDiscovered = "discovered" // Listed by the source, waiting for download
+	StatusDownloaded = "downloaded" // PDF stored, waiting for extraction
+	StatusExtracted  = "extracted"  // Atos and identifiers stored, waiting for indexing
+	StatusIndexed    = "indexed"    // Atos indexed and searchable
+	StatusFailed     = "failed"     // A stage exhausted its retries, see last_error
+	StatusSuperseded = "superseded" // Same PDF as another diario of the institution
+)
+
+// Statuses lists every status in pipeline order
+var Statuses = []string{StatusDiscovered, StatusDownloaded, StatusExtracted, StatusIndexed, StatusFailed, StatusSuperseded}
+
+// transitions maps each status to the statuses a diario can move to from it. Moving to
+// the current status is always allowed, so a retried stage can record it again.
+var transitions = map[string][]string{
+	// A PDF republished once the previous version is extracted is downloaded again as a
+	// new version, and supersedes the diario when it is the PDF of another one
+	StatusDiscovered: {StatusDownloaded, StatusFailed, StatusSuperseded},
+	StatusDownloaded: {StatusExtracted, StatusFailed, StatusSuperseded},
+	StatusExtracted:  {StatusDownloaded, StatusIndexed, StatusFailed, StatusSuperseded},
+	StatusIndexed:    {StatusDownloaded, StatusFailed, StatusSuperseded},
+	// A failed diario resumes from the stage that failed
+	StatusFailed:     {StatusDiscovered, StatusDownloaded, StatusExtracted, StatusIndexed, StatusSuperseded},
+	StatusSuperseded: {},
+}
+
+// statusTimestamps maps each status to the column recording when it was last reached.
+// The discovery is recorded by created_at when the diario is inserted.
+var statusTimestamps = map[string]string{
+	StatusDiscovered: "",
+	StatusDownloaded: "downloaded_at",
+	StatusExtracted:  "extracted_at",
+	StatusIndexed:    "indexed_at",
+	StatusFailed:     "failed_at",
+	StatusSuperseded: "superseded_at",
+}
+
+// ErrInvalidTransition is returned when a diario cannot move to the requested status
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// CanTransition reports whether a diario can move from one status to another
+func CanTransition(from, to string) bool {
+	if _, ok := transitions[to]; !ok {
+		return false
+	}
+	return from == to || slices.Contains(transitions[from], to)
+}
+
+// sourcesOf returns the statuses a diario can move to status from
+func sourcesOf(status string) []string {
+	var from []string
+	for _, s := range Statuses {
+		if CanTransition(s, status) {
+			from = append(from, s)
+		}
+	}
+	return from
+}
+
+func invalidTransition(id int, from, to string) error {
+	return fmt.Errorf("%w: diário %d cannot move from %s to %s", ErrInvalidTransition, id, from, to)
+}
+
+// ResumeStatus returns the status of the last stage a diario completed, whose next stage
+// must run to move it on. A failed diario resumes from the stage that failed, found from
+// the times each status was last reached.
+func ResumeStatus(d *model.Diario) string {
+	if d.Status != StatusFailed {
+		return d.Status
+	}
+
+	switch {
+	case d.DownloadedAt == nil:
+		return StatusDiscovered
+	case d.ExtractedAt == nil || d.ExtractedAt.Before(*d.DownloadedAt):
+		return StatusDownloaded
+	default:
+		return StatusExtracted
+	}
+}
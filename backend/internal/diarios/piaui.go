@@ -1,7 +1,6 @@
 package diarios
 
 import (
-	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
@@ -10,7 +9,6 @@ import (
 	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"path/filepath"
 	"regexp"
 	"runtime"
@@ -21,11 +19,9 @@ import (
 
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
 
 	"radaroficial.app/internal/config"
-	"radaroficial.app/internal/model"
-	"radaroficial.app/internal/storage"
-	"radaroficial.app/internal/weaviate"
 )
 
 type diarioAPIResponse struct {
@@ -36,20 +32,88 @@ type diarioAPIResponse struct {
 }
 
 var diarioURLBase = "https://www.diario.pi.gov.br"
+
+// governoPiauiPageSize is the number of rows requested per page of the DOE-PI listing
+const governoPiauiPageSize = 50
+
 var hrefRegexp = regexp.MustCompile(`href="(.+?\.pdf)"`)
 
-// Institution IDs for database references
-const (
-	InstitutionIDGovernoPiaui    = 1 // ID for Governo do Estado do Piauí
-	InstitutionIDMunicipiosPiaui = 2 // ID for Diário dos Municípios do Piauí
-)
+var municipiosURLBase = "https://www.diarioficialdosmunicipios.org"
+var municipiosEditionRegexp = regexp.MustCompile(`Edição\s+(\d+),\s+(\d{2}/\d{2}/\d{4})`)
+
+// municipiosMaxWalkBack bounds how many past editions Discover visits in a single run
+const municipiosMaxWalkBack = 60
+
+// municipiosEditionURL returns the page of a past Diário dos Municípios edition
+func municipiosEditionURL(number int) string {
+	return fmt.Sprintf("%s/edicao.html?edicao=%d", municipiosURLBase, number)
+}
+
+func init() {
+	RegisterSource(&GovernoPiauiSource{})
+	RegisterSource(&MunicipiosPiauiSource{})
+}
+
+// GovernoPiauiSource fetches the Diário Oficial do Estado do Piauí (DOE-PI)
+type GovernoPiauiSource struct{}
+
+// Metadata describes the Governo do Piauí source
+func (s *GovernoPiauiSource) Metadata() SourceMetadata {
+	return SourceMetadata{
+		Slug:         "governo-pi",
+		Name:         "Governo do Piauí",
+		PollInterval: 1 * time.Hour,
+		Timeout:      10 * time.Minute,
+	}
+}
+
+// Discover lists the diarios published on each day between from and to
+func (s *GovernoPiauiSource) Discover(ctx context.Context, from, to time.Time) ([]Edition, error) {
+	var editions []Edition
+
+	for day := truncateToDay(from); !day.After(truncateToDay(to)); day = day.AddDate(0, 0, 1) {
+		dayEditions, err := s.listDiarios(ctx, day)
+		if err != nil {
+			return nil, err
+		}
+		editions = append(editions, dayEditions...)
+	}
+
+	return editions, nil
+}
 
-// FetchGovernoPiauiDiarios fetches diarios from the Governo do Piauí website, uploads them to storage, and inserts them into the database
-func FetchGovernoPiauiDiarios(ctx context.Context, date time.Time, uploader *storage.SpacesUploader, service *DiarioService) ([]*model.Diario, error) {
+// listDiarios asks the DOE-PI listing API for the diarios published on a date,
+// following the DataTables pagination until every record has been read
+func (s *GovernoPiauiSource) listDiarios(ctx context.Context, date time.Time) ([]Edition, error) {
+	var editions []Edition
+
+	for draw, start := 1, 0; ; draw++ {
+		parsed, err := s.listDiariosPage(ctx, date, draw, start)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range parsed.Data {
+			if edition, ok := parseGovernoPiauiRow(row); ok {
+				editions = append(editions, edition)
+			}
+		}
+
+		start += len(parsed.Data)
+		if len(parsed.Data) == 0 || start >= parsed.RecordsFiltered {
+			break
+		}
+	}
+
+	return editions, nil
+}
+
+// listDiariosPage requests a single page of the DOE-PI listing API
+func (s *GovernoPiauiSource) listDiariosPage(ctx context.Context, date time.Time, draw, start int) (*diarioAPIResponse, error) {
 	form := url.Values{}
-	form.Set("draw", "3")
-	form.Set("start", "0")
-	form.Set("length", "50")
+	form.Set("draw", strconv.Itoa(draw))
+	form.Set("start", strconv.Itoa(start))
+	form.Set("length", strconv.Itoa(governoPiauiPageSize))
 	form.Set("filter_data", date.Format("2006-01-02"))
 
 	for i := 0; i <= 2; i++ {
@@ -89,140 +153,176 @@ func FetchGovernoPiauiDiarios(ctx context.Context, date time.Time, uploader *sto
 		return nil, fmt.Errorf("read response: %w", err)
 	}
 
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing request failed, HTTP status: %d", res.StatusCode)
+	}
+
 	var parsed diarioAPIResponse
 	if err := json.Unmarshal(body, &parsed); err != nil {
 		return nil, fmt.Errorf("parse response: %w", err)
 	}
 
-	var diarios []*model.Diario
-	var processedCount int
+	return &parsed, nil
+}
 
-	for _, row := range parsed.Data {
-		if len(row) < 4 {
-			continue
-		}
+// parseGovernoPiauiRow converts a row of the DOE-PI listing into an Edition
+func parseGovernoPiauiRow(row []string) (Edition, bool) {
+	if len(row) < 4 {
+		return Edition{}, false
+	}
 
-		match := hrefRegexp.FindStringSubmatch(row[0])
-		if len(match) < 2 {
-			continue
-		}
+	match := hrefRegexp.FindStringSubmatch(row[0])
+	if len(match) < 2 {
+		return Edition{}, false
+	}
 
-		publishedAt, _ := time.Parse("02/01/2006", row[2])
-		lastModifiedAt, _ := time.Parse("02/01/2006 15:04:05", row[3])
-		desc := strings.TrimSpace(row[1])
-		sanitized := sanitizeDescription(desc)
+	publishedAt, _ := time.Parse("02/01/2006", row[2])
+	lastModifiedAt, _ := time.Parse("02/01/2006 15:04:05", row[3])
+	desc := strings.TrimSpace(row[1])
 
-		// Check if this diario already exists in our database
-		exists, err := service.Exists(ctx, InstitutionIDGovernoPiaui, desc)
-		if err != nil {
-			log.Printf("⚠️ Error checking if diario exists: %v", err)
-		}
+	rawPDFPath := strings.ReplaceAll(match[1], "..", "")
 
-		if exists {
-			log.Printf("✅ Skipping already downloaded diario: %s", desc)
-			continue
-		}
+	return Edition{
+		Description:    desc,
+		PublishedAt:    publishedAt,
+		LastModifiedAt: &lastModifiedAt,
+		DownloadURL:    diarioURLBase + rawPDFPath,
+		// e.g. "DOEPI_71_2025_DOEPI_71_2025.pdf"
+		Filename: fmt.Sprintf("%s_%s", sanitizeDescription(desc), filepath.Base(rawPDFPath)),
+	}, true
+}
 
-		rawPDFPath := strings.ReplaceAll(match[1], "..", "")
-		pdfURL := diarioURLBase + rawPDFPath
+// Download fetches the PDF of a DOE-PI diario
+func (s *GovernoPiauiSource) Download(ctx context.Context, edition Edition) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", edition.DownloadURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
 
-		// If we get here, this is a new diario that needs downloading
-		log.Printf("📥 Downloading diario %s URL %s", desc, pdfURL)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download PDF: %w", err)
+	}
+	defer resp.Body.Close()
 
-		resp, err := http.Get(pdfURL)
-		if err != nil || resp.StatusCode != http.StatusOK {
-			log.Printf("❌ Failed to download PDF from %s: %v", pdfURL, err)
-			continue
-		}
-		defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download PDF, HTTP status: %d", resp.StatusCode)
+	}
 
-		pdfContent, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Printf("❌ Failed to read PDF content: %v", err)
-			continue
-		}
+	pdfContent, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF content: %w", err)
+	}
 
-		outputDir, err := splitPDFAndConvertToMarkdown(pdfContent)
-		if err != nil {
-			log.Printf("❌ Failed to split PDF: %v", err)
-			continue
-		}
-		err = weaviate.UploadDir(outputDir, desc, "Governo do Estado do Piaui")
-		if err == nil {
-			os.RemoveAll(outputDir)
-		} else {
-			log.Printf("❌ Failed to upload markdown files to weaviate: %v", err)
-			continue
+	return pdfContent, nil
+}
 
-		}
+// MunicipiosPiauiSource fetches the Diário Oficial dos Municípios do Piauí
+type MunicipiosPiauiSource struct{}
 
-		// Construct object path: e.g., "2025/04/DOEPI_71_2025.pdf"
-		filename := filepath.Base(rawPDFPath)
-		objectPath := fmt.Sprintf("governo-pi/%d/%02d/%s_%s", date.Year(), date.Month(), sanitized, filename)
+// Metadata describes the Diário dos Municípios source
+func (s *MunicipiosPiauiSource) Metadata() SourceMetadata {
+	return SourceMetadata{
+		Slug:         "municipios-pi",
+		Name:         "Municípios do Piauí",
+		PollInterval: 1 * time.Hour,
+		Timeout:      1 * time.Hour,
+	}
+}
 
-		err = uploader.UploadFile(ctx, objectPath, bytes.NewReader(pdfContent), int64(len(pdfContent)), "application/pdf")
-		if err != nil {
-			log.Printf("❌ Failed to upload PDF to storage: %v", err)
-			continue
-		}
+// Discover returns the editions of the Diário dos Municípios published between from
+// and to. Editions are numbered sequentially, so it starts at the "edição atual" and
+// walks back edition by edition until it reaches one published before from.
+func (s *MunicipiosPiauiSource) Discover(ctx context.Context, from, to time.Time) ([]Edition, error) {
+	browser, err := launchBrowser()
+	if err != nil {
+		return nil, err
+	}
+	defer browser.MustClose()
 
-		log.Printf("✅ Successfully uploaded %s", objectPath)
+	current, err := s.scrapeEdition(browser, municipiosURLBase+"/edicao_atual.html")
+	if err != nil {
+		return nil, err
+	}
+
+	from, to = truncateToDay(from), truncateToDay(to)
+
+	var editions []Edition
+	edition := current
+
+	for walked := 0; ; walked++ {
+		if !edition.PublishedAt.After(to) && !edition.PublishedAt.Before(from) {
+			editions = append(editions, edition)
+		}
 
-		// Create diario object
-		diario := &model.Diario{
-			InstitutionID:  InstitutionIDGovernoPiaui,
-			SourceURL:      fmt.Sprintf("https://%s.%s/%s", uploader.Bucket, os.Getenv("DO_SPACES_ENDPOINT"), objectPath),
-			Description:    &desc,
-			PublishedAt:    &publishedAt,
-			LastModifiedAt: &lastModifiedAt,
+		if edition.PublishedAt.Before(from) || edition.Number <= 1 {
+			break
 		}
 
-		// Insert directly into database
-		if err := service.Insert(ctx, diario); err != nil {
-			log.Printf("⚠️ Failed to insert diário for %s: %v", diario.SourceURL, err)
-		} else {
-			log.Printf("✅ Inserted diário %s", diario.SourceURL)
-			processedCount++
+		if walked >= municipiosMaxWalkBack {
+			log.Printf("⚠️ Stopped walking back at edição %d, use the gap filler for older editions", edition.Number)
+			break
 		}
 
-		diarios = append(diarios, diario)
+		edition, err = s.scrapeEdition(browser, municipiosEditionURL(edition.Number-1))
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	log.Printf("📊 Successfully processed %d diários from Governo do Piauí", processedCount)
-	return diarios, nil
-}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	log.Printf("📊 Memory used: %.2f MB", float64(m.Alloc)/1024/1024)
 
-// CurrentEditionResponse represents the data structure returned by the Supabase API
-type CurrentEditionResponse struct {
-	Edition int    `json:"edicao"`
-	Date    string `json:"data"`
-	ID      int    `json:"id"`
+	return editions, nil
 }
 
-// FetchDiarioDosMunicipiosPiaui fetches the latest edition of Diário dos Municípios using go-rod
-// and directly inserts it into the database
-func FetchDiarioDosMunicipiosPiaui(ctx context.Context, uploader *storage.SpacesUploader, service *DiarioService) ([]*model.Diario, error) {
-	log.Printf("📥 Fetching Diário dos Municípios using go-rod...")
+// LatestEdition returns the "edição atual" of the Diário dos Municípios
+func (s *MunicipiosPiauiSource) LatestEdition(ctx context.Context) (Edition, error) {
+	browser, err := launchBrowser()
+	if err != nil {
+		return Edition{}, err
+	}
+	defer browser.MustClose()
 
-	// Launch a new browser with headless mode and no-sandbox for Linux compatibility
-	l := launcher.New().
-		Headless(true).
-		Set("no-sandbox", "").
-		Set("disable-setuid-sandbox", "").
-		Set("js-flags", "--max-old-space-size=96") // Limit JS heap to 96MB
+	return s.scrapeEdition(browser, municipiosURLBase+"/edicao_atual.html")
+}
 
-	if config.Env() == "production" {
-		l.Bin("/usr/bin/google-chrome")
+// FetchEdition returns a past edition of the Diário dos Municípios by its number
+func (s *MunicipiosPiauiSource) FetchEdition(ctx context.Context, number int) (Edition, error) {
+	browser, err := launchBrowser()
+	if err != nil {
+		return Edition{}, err
 	}
-	url := l.MustLaunch()
-	browser := rod.New().ControlURL(url).MustConnect()
 	defer browser.MustClose()
 
+	edition, err := s.scrapeEdition(browser, municipiosEditionURL(number))
+	if err != nil {
+		return Edition{}, err
+	}
+
+	if edition.Number != number {
+		return Edition{}, fmt.Errorf("requested edição %d but the page shows edição %d", number, edition.Number)
+	}
+
+	return edition, nil
+}
+
+// scrapeEdition loads an edition page and extracts its number, date and PDF link
+func (s *MunicipiosPiauiSource) scrapeEdition(browser *rod.Browser, pageURL string) (Edition, error) {
+	log.Printf("📥 Fetching Diário dos Municípios page %s using go-rod...", pageURL)
+
 	// Create a new page and navigate to the site
-	page := browser.MustPage("https://www.diarioficialdosmunicipios.org/edicao_atual.html")
+	page, err := browser.Page(proto.TargetCreateTarget{URL: pageURL})
+	if err != nil {
+		return Edition{}, fmt.Errorf("failed to open %s: %w", pageURL, err)
+	}
+	defer page.Close()
 
 	// Wait for the page to load
-	page.MustWaitLoad()
+	if err := page.WaitLoad(); err != nil {
+		return Edition{}, fmt.Errorf("failed to load %s: %w", pageURL, err)
+	}
 	log.Printf("✅ Page loaded successfully")
 
 	// Try to find the edition information with different strategies
@@ -235,23 +335,19 @@ func FetchDiarioDosMunicipiosPiaui(ctx context.Context, uploader *storage.Spaces
 		log.Printf("✅ Found edition text using span#newDesc > h1: %s", editionText)
 	}
 
-	// Try different regex patterns to match edition info
-	var matches []string
-
 	// Try with comma pattern first: "Edição 5302, 16/04/2025"
-	editionRegex := regexp.MustCompile(`Edição\s+(\d+),\s+(\d{2}/\d{2}/\d{4})`)
-	matches = editionRegex.FindStringSubmatch(editionText)
+	matches := municipiosEditionRegexp.FindStringSubmatch(editionText)
 
 	log.Printf("📄 Parsed edition info: %v", matches)
 
 	if len(matches) < 3 {
-		return nil, fmt.Errorf("failed to parse edition information from text: %s", editionText)
+		return Edition{}, fmt.Errorf("failed to parse edition information from text: %s", editionText)
 	}
 
 	// Extract the edition number and date
 	editionNumber, err := strconv.Atoi(matches[1])
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse edition number: %w", err)
+		return Edition{}, fmt.Errorf("failed to parse edition number: %w", err)
 	}
 
 	dateStr := matches[2]
@@ -263,20 +359,6 @@ func FetchDiarioDosMunicipiosPiaui(ctx context.Context, uploader *storage.Spaces
 		publishDate = time.Now()
 	}
 
-	// Create a descriptive title
-	description := fmt.Sprintf("Edição %d (%s)", editionNumber, dateStr)
-
-	// Check if this diario already exists in our database
-	exists, err := service.Exists(ctx, InstitutionIDMunicipiosPiaui, description)
-	if err != nil {
-		log.Printf("⚠️ Error checking if diario exists: %v", err)
-	}
-
-	if exists {
-		log.Printf("✅ Skipping already downloaded diario: %s", description)
-		return nil, nil
-	}
-
 	var pdfURL string
 
 	// Try to find a link containing "Baixar Edição"
@@ -293,17 +375,23 @@ func FetchDiarioDosMunicipiosPiaui(ctx context.Context, uploader *storage.Spaces
 	}
 
 	if pdfURL == "" {
-		return nil, fmt.Errorf("could not find any download link for the PDF")
+		return Edition{}, fmt.Errorf("could not find any download link for the PDF")
 	}
 
 	log.Printf("📄 Found PDF URL: %s", pdfURL)
-	// kill the browser to save memory
-	browser.MustClose()
 
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-	log.Printf("📊 Memory used: %.2f MB", float64(m.Alloc)/1024/1024)
+	return Edition{
+		// Create a descriptive title
+		Description: fmt.Sprintf("Edição %d (%s)", editionNumber, dateStr),
+		PublishedAt: publishDate,
+		DownloadURL: pdfURL,
+		Number:      editionNumber,
+		Filename:    fmt.Sprintf("edicao_%d_%s.pdf", editionNumber, publishDate.Format("2006-01-02")),
+	}, nil
+}
 
+// Download fetches the PDF of a Diário dos Municípios edition
+func (s *MunicipiosPiauiSource) Download(ctx context.Context, edition Edition) ([]byte, error) {
 	// Get the PDF file directly using a standard HTTP request instead of the browser
 	client := &http.Client{
 		Timeout: 1 * time.Hour,
@@ -318,14 +406,14 @@ func FetchDiarioDosMunicipiosPiaui(ctx context.Context, uploader *storage.Spaces
 	}
 
 	// Create request
-	req, err := http.NewRequest("GET", pdfURL, nil) // no context attached
+	req, err := http.NewRequestWithContext(ctx, "GET", edition.DownloadURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
 	req.Header.Set("User-Agent", "Mozilla/5.0")
-	req.Header.Set("Referer", "https://www.diarioficialdosmunicipios.org/edicao_atual.html")
+	req.Header.Set("Referer", municipiosURLBase+"/edicao_atual.html")
 
 	// Send request
 	resp, err := client.Do(req)
@@ -338,56 +426,13 @@ func FetchDiarioDosMunicipiosPiaui(ctx context.Context, uploader *storage.Spaces
 		return nil, fmt.Errorf("failed to download PDF, HTTP status: %d", resp.StatusCode)
 	}
 
-	// Create a temporary file
-	tmpFile, err := os.CreateTemp("", "diario-*.pdf")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
-	}
-	defer tmpFile.Close()
-	defer os.Remove(tmpFile.Name())
-
-	// Write PDF content to file
-	sizeInBytes, err := io.Copy(tmpFile, resp.Body)
+	pdfContent, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to write PDF to temp file: %w", err)
+		return nil, fmt.Errorf("failed to read PDF content: %w", err)
 	}
 
-	log.Printf("📥 Saved PDF to temp file %s (%d bytes)", tmpFile.Name(), sizeInBytes)
-
-	// Format upload path
-	objectPath := fmt.Sprintf("municipios-pi/%d/%02d/edicao_%d_%s.pdf",
-		publishDate.Year(),
-		publishDate.Month(),
-		editionNumber,
-		publishDate.Format("2006-01-02"))
-
-	// Upload to storage
-	err = uploader.UploadFile(ctx, objectPath, tmpFile, sizeInBytes, "application/pdf")
-	if err != nil {
-		return nil, fmt.Errorf("failed to upload PDF: %w", err)
-	}
-
-	log.Printf("✅ Successfully uploaded %s", objectPath)
-
-	// Create the Diario object
-	lastModifiedAt := time.Now()
-
-	diario := &model.Diario{
-		InstitutionID:  InstitutionIDMunicipiosPiaui, // ID for Diário dos Municípios
-		SourceURL:      fmt.Sprintf("https://%s.%s/%s", uploader.Bucket, os.Getenv("DO_SPACES_ENDPOINT"), objectPath),
-		Description:    &description,
-		PublishedAt:    &publishDate,
-		LastModifiedAt: &lastModifiedAt,
-	}
-
-	// Insert directly into database
-	if err := service.Insert(ctx, diario); err != nil {
-		log.Printf("⚠️ Failed to insert diário for %s: %v", diario.SourceURL, err)
-	} else {
-		log.Printf("✅ Inserted diário %s", diario.SourceURL)
-	}
-
-	return []*model.Diario{diario}, nil
+	log.Printf("📥 Downloaded PDF %s (%d bytes)", edition.DownloadURL, len(pdfContent))
+	return pdfContent, nil
 }
 
 // sanitizeDescription returns a safe string for filenames
@@ -408,3 +453,34 @@ func sanitizeDescription(s string) string {
 
 	return safe
 }
+
+// launchBrowser starts a headless browser with no-sandbox for Linux compatibility
+func launchBrowser() (*rod.Browser, error) {
+	l := launcher.New().
+		Headless(true).
+		Set("no-sandbox", "").
+		Set("disable-setuid-sandbox", "").
+		Set("js-flags", "--max-old-space-size=96") // Limit JS heap to 96MB
+
+	if config.Env() == "production" {
+		l.Bin("/usr/bin/google-chrome")
+	}
+
+	url, err := l.Launch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to launch browser: %w", err)
+	}
+
+	browser := rod.New().ControlURL(url)
+	if err := browser.Connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to browser: %w", err)
+	}
+
+	return browser, nil
+}
+
+// truncateToDay returns the calendar date of t at midnight UTC, as the publication dates
+// scraped from the sources are parsed, so they compare by day whatever the zone of t
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
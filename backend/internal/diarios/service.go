@@ -2,13 +2,22 @@ package diarios
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"radaroficial.app/internal/model"
 )
 
 const KNOWLEDGE_BASE_PIAUI_UUID = "a4fc4135-1a22-11f0-bf8f-4e013e2ddde4"
 
+// querier is implemented by both pgxpool.Pool and pgx.Tx
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 type DiarioService struct {
 	DB *pgxpool.Pool
 }
@@ -18,6 +27,16 @@ func NewInstitutionService(db *pgxpool.Pool) *DiarioService {
 }
 
 func (s *DiarioService) Insert(ctx context.Context, d *model.Diario) error {
+	return s.InsertTx(ctx, s.DB, d)
+}
+
+// InsertTx inserts a diario using q, which can be a transaction. When the description
+// already exists nothing is inserted and d.ID stays 0.
+func (s *DiarioService) InsertTx(ctx context.Context, q querier, d *model.Diario) error {
+	if d.Status == "" {
+		d.Status = StatusDiscovered
+	}
+
 	query := `
 		INSERT INTO diarios (
 			institution_id,
@@ -25,21 +44,33 @@ func (s *DiarioService) Insert(ctx context.Context, d *model.Diario) error {
 			last_modified_at,
 			source_url,
 			description,
+			edition_number,
+			sha256,
+			size_bytes,
+			object_key,
+			status,
+			download_url,
 			created_at,
 			updated_at
 		)
-		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
 		ON CONFLICT (institution_id, description) DO NOTHING
-		RETURNING id, created_at, updated_at;
+		RETURNING id, created_at, updated_at, version;
 	`
 
-	err := s.DB.QueryRow(ctx, query,
+	err := q.QueryRow(ctx, query,
 		d.InstitutionID,
 		d.PublishedAt,
 		d.LastModifiedAt,
 		d.SourceURL,
 		d.Description,
-	).Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt)
+		d.EditionNumber,
+		d.SHA256,
+		d.SizeBytes,
+		d.ObjectKey,
+		d.Status,
+		d.DownloadURL,
+	).Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt, &d.Version)
 
 	// If no rows were returned (i.e., conflict triggered), skip Scan
 	if err != nil && err.Error() == "no rows in result set" {
@@ -49,6 +80,12 @@ func (s *DiarioService) Insert(ctx context.Context, d *model.Diario) error {
 	return err
 }
 
+// Delete removes a diario and, by cascade, its atos
+func (s *DiarioService) Delete(ctx context.Context, id int) error {
+	_, err := s.DB.Exec(ctx, `DELETE FROM diarios WHERE id = $1`, id)
+	return err
+}
+
 // Exists checks if a diario already exists in the database
 func (s *DiarioService) Exists(ctx context.Context, institutionID int, description string) (bool, error) {
 	query := `
@@ -64,18 +101,157 @@ func (s *DiarioService) Exists(ctx context.Context, institutionID int, descripti
 	return exists, err
 }
 
-func (s *DiarioService) GetPendingIndexing(ctx context.Context) ([]*model.Diario, error) {
+// diarioColumns are the columns read by scanDiario
+const diarioColumns = `
+	id, institution_id, published_at, last_modified_at,
+	COALESCE(source_url, ''), description, edition_number, sha256, size_bytes, object_key,
+	page_count, version, status, download_url, status_changed_at, downloaded_at, extracted_at,
+	indexed_at, failed_at, superseded_at, last_error, last_error_at, created_at, updated_at
+`
+
+func scanDiario(row pgx.Row) (*model.Diario, error) {
+	d := &model.Diario{}
+	err := row.Scan(
+		&d.ID, &d.InstitutionID, &d.PublishedAt, &d.LastModifiedAt,
+		&d.SourceURL, &d.Description, &d.EditionNumber, &d.SHA256, &d.SizeBytes, &d.ObjectKey,
+		&d.PageCount, &d.Version, &d.Status, &d.DownloadURL, &d.StatusChangedAt, &d.DownloadedAt, &d.ExtractedAt,
+		&d.IndexedAt, &d.FailedAt, &d.SupersededAt, &d.LastError, &d.LastErrorAt, &d.CreatedAt, &d.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// GetByID returns the diario with the given ID, or nil when there is none
+func (s *DiarioService) GetByID(ctx context.Context, id int) (*model.Diario, error) {
+	query := `SELECT ` + diarioColumns + ` FROM diarios WHERE id = $1;`
+
+	d, err := scanDiario(s.DB.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return d, err
+}
+
+// GetByDescription returns the diario of an institution with the given description,
+// or nil when there is none
+func (s *DiarioService) GetByDescription(ctx context.Context, institutionID int, description string) (*model.Diario, error) {
+	query := `SELECT ` + diarioColumns + ` FROM diarios WHERE institution_id = $1 AND description = $2;`
+
+	d, err := scanDiario(s.DB.QueryRow(ctx, query, institutionID, description))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return d, err
+}
+
+// GetByEditionNumber returns the diario of an institution with the given edition number,
+// or nil when there is none
+func (s *DiarioService) GetByEditionNumber(ctx context.Context, institutionID int, number int) (*model.Diario, error) {
+	query := `SELECT ` + diarioColumns + ` FROM diarios
+		WHERE institution_id = $1 AND edition_number = $2
+		ORDER BY id ASC
+		LIMIT 1;
+	`
+
+	d, err := scanDiario(s.DB.QueryRow(ctx, query, institutionID, number))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return d, err
+}
+
+// FirstEditionSince returns the lowest edition number stored for an institution among
+// those published since day, or 0 when there is none
+func (s *DiarioService) FirstEditionSince(ctx context.Context, institutionID int, day time.Time) (int, error) {
+	query := `
+		SELECT COALESCE(MIN(edition_number), 0) FROM diarios
+		WHERE institution_id = $1 AND published_at >= $2;
+	`
+
+	var number int
+	err := s.DB.QueryRow(ctx, query, institutionID, day).Scan(&number)
+	return number, err
+}
+
+// Transition moves a diario to another lifecycle status, recording when it was reached.
+// It fails with ErrInvalidTransition when the current status does not allow it. q can be
+// the transaction that enqueues the job of the next stage so both are committed together.
+func (s *DiarioService) Transition(ctx context.Context, q querier, id int, to string) error {
+	return s.transition(ctx, q, id, to, nil)
+}
+
+// MarkFailed moves a diario to the failed status, recording the error
+func (s *DiarioService) MarkFailed(ctx context.Context, id int, cause error) error {
+	message := cause.Error()
+	return s.transition(ctx, s.DB, id, StatusFailed, &message)
+}
+
+func (s *DiarioService) transition(ctx context.Context, q querier, id int, to string, lastError *string) error {
+	column, ok := statusTimestamps[to]
+	if !ok {
+		return fmt.Errorf("unknown status %q", to)
+	}
+
+	setTimestamp := ""
+	if column != "" {
+		setTimestamp = column + " = NOW(),"
+	}
+
 	query := `
-		SELECT 
-			id, institution_id, published_at, last_modified_at, 
-			source_url, description, 
-			created_at, updated_at, indexing_submitted_at
+		UPDATE diarios
+		SET
+			status = $2,
+			status_changed_at = NOW(),
+			` + setTimestamp + `
+			last_error = COALESCE($4, last_error),
+			last_error_at = CASE WHEN $4::text IS NULL THEN last_error_at ELSE NOW() END,
+			updated_at = NOW()
+		WHERE id = $1 AND status = ANY($3)
+		RETURNING id;
+	`
+
+	var updated int
+	err := q.QueryRow(ctx, query, id, to, sourcesOf(to), lastError).Scan(&updated)
+	if errors.Is(err, pgx.ErrNoRows) {
+		current, err := s.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return fmt.Errorf("diário %d not found", id)
+		}
+		return invalidTransition(id, current.Status, to)
+	}
+
+	return err
+}
+
+// RecordError records the error of a failed attempt of a stage, without changing the
+// status, as the stage is retried
+func (s *DiarioService) RecordError(ctx context.Context, id int, cause error) error {
+	query := `
+		UPDATE diarios
+		SET last_error = $2, last_error_at = NOW(), updated_at = NOW()
+		WHERE id = $1;
+	`
+
+	_, err := s.DB.Exec(ctx, query, id, cause.Error())
+	return err
+}
+
+// ListStuck returns the diarios that have been in a status for longer than olderThan,
+// oldest first. Diarios stuck in an intermediate status point to jobs that were lost.
+func (s *DiarioService) ListStuck(ctx context.Context, status string, olderThan time.Duration, limit int) ([]*model.Diario, error) {
+	query := `SELECT ` + diarioColumns + `
 		FROM diarios
-		WHERE indexing_submitted_at IS NULL
-		ORDER BY id ASC;
+		WHERE status = $1 AND status_changed_at < NOW() - make_interval(secs => $2)
+		ORDER BY status_changed_at ASC
+		LIMIT $3;
 	`
 
-	rows, err := s.DB.Query(ctx, query)
+	rows, err := s.DB.Query(ctx, query, status, olderThan.Seconds(), limit)
 	if err != nil {
 		return nil, err
 	}
@@ -83,12 +259,7 @@ func (s *DiarioService) GetPendingIndexing(ctx context.Context) ([]*model.Diario
 
 	var diarios []*model.Diario
 	for rows.Next() {
-		d := &model.Diario{}
-		err := rows.Scan(
-			&d.ID, &d.InstitutionID, &d.PublishedAt, &d.LastModifiedAt,
-			&d.SourceURL, &d.Description,
-			&d.CreatedAt, &d.UpdatedAt, &d.IndexingSubmittedAt,
-		)
+		d, err := scanDiario(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -102,15 +273,241 @@ func (s *DiarioService) GetPendingIndexing(ctx context.Context) ([]*model.Diario
 	return diarios, nil
 }
 
-func (s *DiarioService) MarkAsIndexingSubmitted(ctx context.Context, institutionIds []int) error {
+// CountByStatus returns the number of diarios in each status, and of those that have
+// been in it for longer than olderThan
+func (s *DiarioService) CountByStatus(ctx context.Context, olderThan time.Duration) (map[string]int, map[string]int, error) {
 	query := `
+		SELECT
+			status,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status_changed_at < NOW() - make_interval(secs => $1))
+		FROM diarios
+		GROUP BY status;
+	`
+
+	rows, err := s.DB.Query(ctx, query, olderThan.Seconds())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	stuck := map[string]int{}
+	for rows.Next() {
+		var status string
+		var count, stuckCount int
+		if err := rows.Scan(&status, &count, &stuckCount); err != nil {
+			return nil, nil, err
+		}
+		counts[status] = count
+		stuck[status] = stuckCount
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return counts, stuck, nil
+}
+
+// RecordPDF records the PDF set on d by Ingester.Download. A PDF replacing a stored one
+// archives it in diario_versions and increments the version. Recording the same PDF
+// again is a no-op. tx is the transaction that enqueues the extraction of the PDF.
+func (s *DiarioService) RecordPDF(ctx context.Context, tx pgx.Tx, d *model.Diario) error {
+	archive := `
+		INSERT INTO diario_versions (
+			diario_id, version, source_url, last_modified_at, sha256, size_bytes, object_key, superseded_at
+		)
+		SELECT id, version, source_url, last_modified_at, sha256, size_bytes, object_key, NOW()
+		FROM diarios
+		WHERE id = $1 AND object_key IS NOT NULL AND sha256 IS DISTINCT FROM $2;
+	`
+
+	if _, err := tx.Exec(ctx, archive, d.ID, d.SHA256); err != nil {
+		return fmt.Errorf("failed to archive version of diario %d: %w", d.ID, err)
+	}
+
+	update := `
 		UPDATE diarios
-		SET 
-			indexing_submitted_at = NOW(),
+		SET
+			source_url = $2,
+			last_modified_at = COALESCE($3, last_modified_at),
+			sha256 = $4,
+			size_bytes = $5,
+			object_key = $6,
+			download_url = $7,
+			version = CASE WHEN object_key IS NULL THEN version ELSE version + 1 END,
 			updated_at = NOW()
-		WHERE institution_id = ANY($1) AND indexing_submitted_at IS NULL ;
+		WHERE id = $1 AND sha256 IS DISTINCT FROM $4
+		RETURNING version, updated_at;
 	`
 
-	_, err := s.DB.Exec(ctx, query, institutionIds)
+	err := tx.QueryRow(ctx, update, d.ID, d.SourceURL, d.LastModifiedAt, d.SHA256, d.SizeBytes, d.ObjectKey, d.DownloadURL).
+		Scan(&d.Version, &d.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Recorded by a previous attempt
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update diario %d: %w", d.ID, err)
+	}
+
+	return nil
+}
+
+// TouchLastModified records a newer modification time for a diario whose PDF did not change
+func (s *DiarioService) TouchLastModified(ctx context.Context, id int, lastModifiedAt time.Time) error {
+	query := `
+		UPDATE diarios
+		SET last_modified_at = $2, updated_at = NOW()
+		WHERE id = $1;
+	`
+
+	_, err := s.DB.Exec(ctx, query, id, lastModifiedAt)
+	return err
+}
+
+// RecordPageCount records the number of pages of the PDF of a diario, found on extraction
+func (s *DiarioService) RecordPageCount(ctx context.Context, id int, pageCount int) error {
+	query := `
+		UPDATE diarios
+		SET page_count = $2, updated_at = NOW()
+		WHERE id = $1;
+	`
+
+	_, err := s.DB.Exec(ctx, query, id, pageCount)
 	return err
 }
+
+// ListByInstitution returns the diarios of an institution published in [from, to), when
+// given, newest first, and how many there are in total. Superseded diarios are left out.
+func (s *DiarioService) ListByInstitution(ctx context.Context, institutionID int, from, to *time.Time, limit, offset int) ([]*model.Diario, int, error) {
+	where := `
+		WHERE institution_id = $1 AND status <> 'superseded'
+			AND ($2::timestamp IS NULL OR published_at >= $2)
+			AND ($3::timestamp IS NULL OR published_at < $3)
+	`
+
+	var total int
+	if err := s.DB.QueryRow(ctx, `SELECT COUNT(*) FROM diarios `+where, institutionID, from, to).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count diarios: %w", err)
+	}
+
+	query := `SELECT ` + diarioColumns + ` FROM diarios ` + where + `
+		ORDER BY published_at DESC NULLS LAST, id DESC
+		LIMIT $4 OFFSET $5;
+	`
+
+	rows, err := s.DB.Query(ctx, query, institutionID, from, to, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list diarios: %w", err)
+	}
+	defer rows.Close()
+
+	diarios := []*model.Diario{}
+	for rows.Next() {
+		d, err := scanDiario(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan diario: %w", err)
+		}
+		diarios = append(diarios, d)
+	}
+
+	return diarios, total, rows.Err()
+}
+
+// ListVersions returns the previous versions of a diario, newest first
+func (s *DiarioService) ListVersions(ctx context.Context, diarioID int) ([]*model.DiarioVersion, error) {
+	query := `
+		SELECT
+			id, diario_id, version, source_url, last_modified_at,
+			sha256, size_bytes, object_key, superseded_at
+		FROM diario_versions
+		WHERE diario_id = $1
+		ORDER BY version DESC;
+	`
+
+	rows, err := s.DB.Query(ctx, query, diarioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []*model.DiarioVersion
+	for rows.Next() {
+		v := &model.DiarioVersion{}
+		err := rows.Scan(
+			&v.ID, &v.DiarioID, &v.Version, &v.SourceURL, &v.LastModifiedAt,
+			&v.SHA256, &v.SizeBytes, &v.ObjectKey, &v.SupersededAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// FindBySHA256 returns the description of the diario of an institution, other than
+// excludeID, whose PDF has the given checksum, or nil when there is none
+func (s *DiarioService) FindBySHA256(ctx context.Context, institutionID, excludeID int, sha256 string) (*string, error) {
+	query := `
+		SELECT description FROM diarios
+		WHERE institution_id = $1 AND sha256 = $2 AND id <> $3
+		LIMIT 1;
+	`
+
+	var description *string
+	err := s.DB.QueryRow(ctx, query, institutionID, sha256, excludeID).Scan(&description)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if description == nil {
+		description = new(string)
+	}
+	return description, nil
+}
+
+// FindEditionGaps returns the edition numbers missing from the sequence stored for an
+// institution, from first up to latest (inclusive)
+func (s *DiarioService) FindEditionGaps(ctx context.Context, institutionID int, first, latest int) ([]int, error) {
+	query := `
+		SELECT n
+		FROM generate_series($2::int, $3::int) AS n
+		WHERE NOT EXISTS (
+			SELECT 1 FROM diarios
+			WHERE institution_id = $1 AND edition_number = n
+		)
+		ORDER BY n ASC;
+	`
+
+	rows, err := s.DB.Query(ctx, query, institutionID, first, latest)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var gaps []int
+	for rows.Next() {
+		var n int
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		gaps = append(gaps, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return gaps, nil
+}
@@ -0,0 +1,96 @@
+package diarios
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Edition describes a single gazette edition discovered on a source website
+type Edition struct {
+	Description    string
+	PublishedAt    time.Time
+	LastModifiedAt *time.Time
+	DownloadURL    string
+	Number         int    // Edition number, when the source exposes one
+	Filename       string // File name used when storing the PDF
+}
+
+// SourceMetadata describes a gazette source and how often it should be polled
+type SourceMetadata struct {
+	Slug         string        // Matches institutions.slug
+	Name         string        // Human readable name used in logs
+	PollInterval time.Duration // Interval of the periodic fetch job
+	Timeout      time.Duration // Maximum execution time of a fetch job
+}
+
+// DiarioSource is implemented by every gazette adapter. Adding a new gazette
+// means writing one DiarioSource, registering it and inserting the matching
+// institution row.
+type DiarioSource interface {
+	// Metadata describes the source
+	Metadata() SourceMetadata
+
+	// Discover lists the editions published between from and to (inclusive)
+	Discover(ctx context.Context, from, to time.Time) ([]Edition, error)
+
+	// Download fetches the PDF content of an edition
+	Download(ctx context.Context, edition Edition) ([]byte, error)
+}
+
+// NumberedSource is implemented by sources whose editions are numbered sequentially
+// and can be fetched by number, which allows detecting and filling gaps
+type NumberedSource interface {
+	DiarioSource
+
+	// LatestEdition returns the most recent edition published
+	LatestEdition(ctx context.Context) (Edition, error)
+
+	// FetchEdition returns the edition with the given number
+	FetchEdition(ctx context.Context, number int) (Edition, error)
+}
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = map[string]DiarioSource{}
+)
+
+// RegisterSource adds a source to the registry, keyed by its slug
+func RegisterSource(src DiarioSource) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+
+	slug := src.Metadata().Slug
+	if _, exists := sources[slug]; exists {
+		panic(fmt.Sprintf("diarios: source %q registered twice", slug))
+	}
+	sources[slug] = src
+}
+
+// GetSource returns the source registered for an institution slug
+func GetSource(slug string) (DiarioSource, bool) {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+
+	src, ok := sources[slug]
+	return src, ok
+}
+
+// Sources returns all registered sources sorted by slug
+func Sources() []DiarioSource {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+
+	list := make([]DiarioSource, 0, len(sources))
+	for _, src := range sources {
+		list = append(list, src)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Metadata().Slug < list[j].Metadata().Slug
+	})
+
+	return list
+}
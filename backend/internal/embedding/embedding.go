@@ -0,0 +1,134 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"radaroficial.app/internal/model"
+)
+
+const (
+	defaultOpenAIModel = "text-embedding-3-small"
+	defaultDimensions  = 1536
+)
+
+// Embedder computes the vectors atos and queries are indexed and searched by
+type Embedder interface {
+	// Embed returns the embedding of every text, in order
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Model names the model producing the embeddings, recorded with every stored vector
+	Model() string
+	// Dimensions is the length of every embedding
+	Dimensions() int
+}
+
+// NewEmbedderFromEnv creates the embedder selected by EMBEDDER: openai (default), any
+// OpenAI-compatible API at OPENAI_BASE_URL, or local, deterministic and offline.
+// EMBEDDING_MODEL and EMBEDDING_DIMENSIONS select the model and its dimensions, which are
+// only sent to the API when set, as models other than text-embedding-3 reject them.
+func NewEmbedderFromEnv() (Embedder, error) {
+	dimensions := defaultDimensions
+	v := os.Getenv("EMBEDDING_DIMENSIONS")
+	if v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid EMBEDDING_DIMENSIONS %q", v)
+		}
+		dimensions = d
+	}
+
+	switch backend := os.Getenv("EMBEDDER"); backend {
+	case "", "openai":
+		apiKey := os.Getenv("OPENAI_API_KEY")
+		if apiKey == "" {
+			return nil, fmt.Errorf("OPENAI_API_KEY environment variable not set")
+		}
+
+		model := os.Getenv("EMBEDDING_MODEL")
+		if model == "" {
+			model = defaultOpenAIModel
+		}
+
+		return NewOpenAIEmbedder(OpenAIConfig{
+			BaseURL:        os.Getenv("OPENAI_BASE_URL"),
+			APIKey:         apiKey,
+			Model:          model,
+			Dimensions:     dimensions,
+			SendDimensions: v != "",
+		}), nil
+	case "local":
+		return NewLocalEmbedder(dimensions), nil
+	default:
+		return nil, fmt.Errorf("unknown EMBEDDER %q, expected openai or local", backend)
+	}
+}
+
+// CountTokens estimates the number of tokens of text for OpenAI tokenizers: words
+// average about four bytes per token, and every punctuation mark is a token of its own
+func CountTokens(text string) int {
+	tokens := 0
+	wordBytes := 0
+
+	flush := func() {
+		if wordBytes > 0 {
+			tokens += (wordBytes + 3) / 4
+			wordBytes = 0
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			wordBytes += utf8.RuneLen(r)
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// TruncateTokens shortens text to about maxTokens tokens, cutting at a space
+func TruncateTokens(text string, maxTokens int) string {
+	if CountTokens(text) <= maxTokens {
+		return text
+	}
+
+	// Bisect on the rune length, token counts grow with the prefix
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if CountTokens(string(runes[:mid])) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	truncated := string(runes[:lo])
+	if i := strings.LastIndexFunc(truncated, unicode.IsSpace); i > 0 {
+		truncated = truncated[:i]
+	}
+	return truncated
+}
+
+// AtoText returns the text embedded for an ato: the same context the weaviate vectorizer
+// module sees, with the diario and the institution before the ato itself
+func AtoText(diario *model.Diario, institution *model.Institution, ato model.Ato) string {
+	description := ""
+	if diario.Description != nil {
+		description = *diario.Description
+	}
+
+	return strings.Join([]string{description, institution.Name, ato.Title, ato.Content}, "\n")
+}
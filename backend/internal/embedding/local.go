@@ -0,0 +1,98 @@
+package embedding
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// LocalModel names the embeddings of the LocalEmbedder, bump it when they change
+const LocalModel = "local-hash-v1"
+
+// LocalEmbedder computes deterministic embeddings offline by hashing the words and
+// character trigrams of a text into the dimensions of the vector. Texts sharing words
+// are close, which is enough for tests and development, not for semantic retrieval.
+type LocalEmbedder struct {
+	dimensions int
+}
+
+// NewLocalEmbedder creates a new LocalEmbedder
+func NewLocalEmbedder(dimensions int) *LocalEmbedder {
+	return &LocalEmbedder{dimensions: dimensions}
+}
+
+// Model returns LocalModel
+func (e *LocalEmbedder) Model() string { return LocalModel }
+
+// Dimensions returns the length of the embeddings
+func (e *LocalEmbedder) Dimensions() int { return e.dimensions }
+
+// Embed returns the embedding of every text, in order
+func (e *LocalEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i] = e.embed(text)
+	}
+	return embeddings, nil
+}
+
+func (e *LocalEmbedder) embed(text string) []float32 {
+	vector := make([]float32, e.dimensions)
+
+	words := strings.FieldsFunc(fold(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, word := range words {
+		e.add(vector, "w:"+word, 1)
+
+		// Trigrams bring inflections of a word close, e.g. "nomeado" and "nomeada"
+		padded := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			e.add(vector, "t:"+string(padded[i:i+3]), 0.5)
+		}
+	}
+
+	// Unit length, so cosine similarity is the dot product
+	var squares float64
+	for _, v := range vector {
+		squares += float64(v) * float64(v)
+	}
+	if squares > 0 {
+		scale := float32(1 / math.Sqrt(squares))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+
+	return vector
+}
+
+// add hashes feature into a dimension, the sign also coming from the hash so that
+// collisions cancel out on average
+func (e *LocalEmbedder) add(vector []float32, feature string, weight float32) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+
+	if sum&(1<<63) != 0 {
+		weight = -weight
+	}
+	vector[sum%uint64(len(vector))] += weight
+}
+
+// fold lowercases s and removes its accents
+func fold(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		folded = s
+	}
+	return strings.ToLower(folded)
+}
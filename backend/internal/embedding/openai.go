@@ -0,0 +1,222 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// maxInputTokens keeps every input below the 8191 tokens accepted by the API, with
+	// room for the error of CountTokens
+	maxInputTokens = 7000
+
+	// maxBatchTokens and maxBatchInputs bound each request below the API limits
+	maxBatchTokens = 100000
+	maxBatchInputs = 128
+
+	// maxRetries bounds the attempts of a request rate limited or failed by the server
+	maxRetries = 5
+)
+
+// OpenAIConfig configures an OpenAIEmbedder
+type OpenAIConfig struct {
+	BaseURL    string // Defaults to https://api.openai.com/v1
+	APIKey     string
+	Model      string
+	Dimensions int // Length of the embeddings returned, checked on every response
+
+	// SendDimensions asks the API for embeddings of Dimensions, as text-embedding-3 models
+	// shorten them. Other models and many compatible servers reject the parameter.
+	SendDimensions bool
+}
+
+// OpenAIEmbedder computes embeddings with the OpenAI embeddings API or any API
+// compatible with it. Texts are sent in batches bounded by count and tokens, and rate
+// limited requests are retried with backoff.
+type OpenAIEmbedder struct {
+	config OpenAIConfig
+	client *http.Client
+
+	// TokensUsed counts the tokens billed by the API since the embedder was created
+	TokensUsed atomic.Int64
+}
+
+// NewOpenAIEmbedder creates a new OpenAIEmbedder
+func NewOpenAIEmbedder(config OpenAIConfig) *OpenAIEmbedder {
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.openai.com/v1"
+	}
+
+	return &OpenAIEmbedder{
+		config: config,
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Model returns the name of the embedding model
+func (e *OpenAIEmbedder) Model() string { return e.config.Model }
+
+// Dimensions returns the length of the embeddings
+func (e *OpenAIEmbedder) Dimensions() int { return e.config.Dimensions }
+
+// Embed returns the embedding of every text, in order
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, 0, len(texts))
+
+	var batch []string
+	batchTokens := 0
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		result, err := e.embedBatch(ctx, batch)
+		if err != nil {
+			return err
+		}
+
+		embeddings = append(embeddings, result...)
+		batch, batchTokens = nil, 0
+		return nil
+	}
+
+	for _, text := range texts {
+		text = TruncateTokens(text, maxInputTokens)
+		tokens := CountTokens(text)
+
+		if len(batch) == maxBatchInputs || batchTokens+tokens > maxBatchTokens {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+
+		batch = append(batch, text)
+		batchTokens += tokens
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return embeddings, nil
+}
+
+// errRetryable marks responses worth retrying: rate limits and server errors
+var errRetryable = errors.New("retryable embeddings error")
+
+func (e *OpenAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	request := map[string]any{
+		"model": e.config.Model,
+		"input": texts,
+	}
+	if e.config.SendDimensions {
+		request["dimensions"] = e.config.Dimensions
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		embeddings, retryAfter, err := e.request(ctx, payload, len(texts))
+		if err == nil {
+			return embeddings, nil
+		}
+
+		if !errors.Is(err, errRetryable) || attempt == maxRetries {
+			return nil, err
+		}
+
+		// Exponential backoff from 1s unless the API tells how long to wait
+		wait := time.Duration(1<<attempt) * time.Second
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+
+		log.Printf("⏳ Embeddings request failed (%v), retrying in %s", err, wait)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// request sends one embeddings request, returning how long to wait before retrying when
+// the API says so
+func (e *OpenAIEmbedder) request(ctx context.Context, payload []byte, count int) ([][]float32, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", e.config.BaseURL+"/embeddings", bytes.NewReader(payload))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.config.APIKey)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", errRetryable, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: failed to read response: %v", errRetryable, err)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		retryAfter := time.Duration(0)
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, retryAfter, fmt.Errorf("%w: status %d: %s", errRetryable, resp.StatusCode, body)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, 0, fmt.Errorf("embeddings API error (status %d): %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			TotalTokens int64 `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse embeddings response: %w", err)
+	}
+
+	if len(result.Data) != count {
+		return nil, 0, fmt.Errorf("embeddings API returned %d embeddings for %d texts", len(result.Data), count)
+	}
+
+	embeddings := make([][]float32, count)
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= count {
+			return nil, 0, fmt.Errorf("embeddings API returned index %d for %d texts", d.Index, count)
+		}
+		if len(d.Embedding) != e.config.Dimensions {
+			return nil, 0, fmt.Errorf("embeddings API returned %d dimensions, expected %d; set EMBEDDING_DIMENSIONS to the dimensions of %s",
+				len(d.Embedding), e.config.Dimensions, e.config.Model)
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+
+	e.TokensUsed.Add(result.Usage.TotalTokens)
+
+	return embeddings, 0, nil
+}
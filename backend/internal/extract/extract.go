@@ -0,0 +1,59 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+// Page is the Markdown content extracted from a single PDF page
+type Page struct {
+	Number        int // 1-based page number
+	Markdown      string
+	OCR           bool    // Whether the text was recognized by OCR
+	OCRConfidence float64 // OCR confidence between 0 and 1, set when OCR is true
+}
+
+// Converter splits a PDF into pages and converts each page to Markdown
+type Converter interface {
+	Convert(ctx context.Context, pdfContent []byte) ([]Page, error)
+}
+
+// NewConverterFromEnv returns the converter selected by PDF_CONVERTER, which can be
+// "native" (default, in-process) or "python" (scripts/split_and_convert_pdf.py).
+// Unless OCR_ENABLED is "false", pages with fewer than OCR_MIN_CHARS characters
+// are recognized with tesseract using the OCR_LANGUAGE model.
+func NewConverterFromEnv() (Converter, error) {
+	var converter Converter
+
+	switch name := os.Getenv("PDF_CONVERTER"); name {
+	case "", "native":
+		converter = NewNativeConverter()
+	case "python":
+		converter = NewPythonConverter(os.Getenv("PDF_CONVERTER_PYTHON"), os.Getenv("PDF_CONVERTER_SCRIPT"))
+	default:
+		return nil, fmt.Errorf("unknown PDF_CONVERTER %q, expected native or python", name)
+	}
+
+	if os.Getenv("OCR_ENABLED") == "false" {
+		return converter, nil
+	}
+
+	engine, err := NewTesseractOCR(os.Getenv("OCR_LANGUAGE"))
+	if err != nil {
+		log.Printf("⚠️ OCR disabled: %v", err)
+		return converter, nil
+	}
+
+	minChars := 50
+	if v := os.Getenv("OCR_MIN_CHARS"); v != "" {
+		minChars, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OCR_MIN_CHARS %q: %w", v, err)
+		}
+	}
+
+	return NewOCRConverter(converter, engine, minChars), nil
+}
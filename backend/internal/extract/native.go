@@ -0,0 +1,407 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// NativeConverter extracts text in-process and rebuilds the page layout as Markdown:
+// larger or bold uppercase lines become headings, consecutive lines become paragraphs
+// and runs of lines split into aligned cells become Markdown tables.
+type NativeConverter struct{}
+
+// NewNativeConverter creates a NativeConverter
+func NewNativeConverter() *NativeConverter {
+	return &NativeConverter{}
+}
+
+// Convert splits the PDF into pages and converts each page to Markdown
+func (c *NativeConverter) Convert(ctx context.Context, pdfContent []byte) ([]Page, error) {
+	reader, total, err := openPDF(pdfContent)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]Page, 0, total)
+
+	for i := 1; i <= total; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		markdown, err := pageToMarkdown(reader, i)
+		if err != nil {
+			// A broken page must not lose the whole edition
+			log.Printf("⚠️ Error processing page %d: %v", i, err)
+		}
+
+		pages = append(pages, Page{Number: i, Markdown: markdown})
+	}
+
+	log.Printf("📄 Converted %d pages to Markdown", total)
+	return pages, nil
+}
+
+// openPDF opens a PDF and counts its pages, failing on a malformed file rather than
+// panicking as the pdf package does
+func openPDF(pdfContent []byte) (reader *pdf.Reader, total int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			reader, total = nil, 0
+			err = fmt.Errorf("failed to open PDF: malformed file: %v", r)
+		}
+	}()
+
+	reader, err = pdf.NewReader(bytes.NewReader(pdfContent), int64(len(pdfContent)))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open PDF: %w", err)
+	}
+
+	return reader, reader.NumPage(), nil
+}
+
+// textCell is a run of text within a line, separated from its neighbours by a wide gap
+type textCell struct {
+	X    float64
+	Text string
+}
+
+// textLine is a visual line of the page
+type textLine struct {
+	Y     float64
+	Size  float64
+	Bold  bool
+	Cells []textCell
+}
+
+func (l textLine) text() string {
+	parts := make([]string, len(l.Cells))
+	for i, cell := range l.Cells {
+		parts[i] = cell.Text
+	}
+	return strings.Join(parts, " ")
+}
+
+// pageToMarkdown converts the text drawn on page number of a PDF to Markdown
+func pageToMarkdown(reader *pdf.Reader, number int) (markdown string, err error) {
+	// The pdf package panics on malformed page trees and content streams
+	defer func() {
+		if r := recover(); r != nil {
+			markdown = ""
+			err = fmt.Errorf("malformed page: %v", r)
+		}
+	}()
+
+	page := reader.Page(number)
+	if page.V.IsNull() {
+		return "", nil
+	}
+
+	lines := groupLines(page.Content().Text)
+	if len(lines) == 0 {
+		return "", nil
+	}
+
+	return renderMarkdown(lines, bodyFontSize(lines)), nil
+}
+
+// groupLines clusters the glyphs drawn on a page into lines, top to bottom
+func groupLines(texts []pdf.Text) []textLine {
+	var glyphs []pdf.Text
+	for _, t := range texts {
+		if t.S != "" {
+			glyphs = append(glyphs, t)
+		}
+	}
+
+	sort.SliceStable(glyphs, func(i, j int) bool {
+		if math.Abs(glyphs[i].Y-glyphs[j].Y) > 0.5 {
+			return glyphs[i].Y > glyphs[j].Y
+		}
+		return glyphs[i].X < glyphs[j].X
+	})
+
+	var groups [][]pdf.Text
+	for _, g := range glyphs {
+		n := len(groups)
+		if n > 0 {
+			last := groups[n-1]
+			tolerance := math.Max(last[0].FontSize, g.FontSize) * 0.4
+			if math.Abs(last[0].Y-g.Y) <= tolerance {
+				groups[n-1] = append(last, g)
+				continue
+			}
+		}
+		groups = append(groups, []pdf.Text{g})
+	}
+
+	lines := make([]textLine, 0, len(groups))
+	for _, group := range groups {
+		if line, ok := buildLine(group); ok {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// buildLine joins the glyphs of a line into words and cells
+func buildLine(glyphs []pdf.Text) (textLine, bool) {
+	sort.SliceStable(glyphs, func(i, j int) bool { return glyphs[i].X < glyphs[j].X })
+
+	line := textLine{Y: glyphs[0].Y}
+
+	var current strings.Builder
+	cellX := glyphs[0].X
+	var boldChars, totalChars int
+	end := glyphs[0].X
+
+	flush := func() {
+		if text := strings.TrimSpace(current.String()); text != "" {
+			line.Cells = append(line.Cells, textCell{X: cellX, Text: collapseSpaces(text)})
+		}
+		current.Reset()
+	}
+
+	for i, g := range glyphs {
+		size := g.FontSize
+		if size <= 0 {
+			size = 10
+		}
+
+		if i > 0 {
+			gap := g.X - end
+			switch {
+			case gap > math.Max(2.5*size, 18):
+				// Wide gap: new table cell or column
+				flush()
+				cellX = g.X
+			case gap > 0.2*size:
+				current.WriteByte(' ')
+			}
+		}
+
+		current.WriteString(g.S)
+		end = math.Max(end, g.X+g.W)
+
+		chars := len([]rune(strings.TrimSpace(g.S)))
+		totalChars += chars
+		if isBoldFont(g.Font) {
+			boldChars += chars
+		}
+		line.Size = math.Max(line.Size, size)
+	}
+	flush()
+
+	line.Bold = totalChars > 0 && boldChars*2 > totalChars
+	return line, len(line.Cells) > 0
+}
+
+// bodyFontSize returns the font size used by most of the text on the page
+func bodyFontSize(lines []textLine) float64 {
+	weights := map[float64]int{}
+	for _, line := range lines {
+		weights[math.Round(line.Size)] += len(line.text())
+	}
+
+	var body float64
+	var best int
+	for size, weight := range weights {
+		if weight > best || (weight == best && size < body) {
+			body, best = size, weight
+		}
+	}
+
+	return body
+}
+
+// renderMarkdown classifies lines into headings, tables and paragraphs
+func renderMarkdown(lines []textLine, body float64) string {
+	var blocks []string
+	var paragraph []string
+	var table [][]string
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, joinParagraph(paragraph))
+			paragraph = nil
+		}
+	}
+
+	flushTable := func() {
+		switch {
+		case len(table) >= 2:
+			blocks = append(blocks, renderTable(table))
+		case len(table) == 1:
+			// A single row split in cells is just a spaced out line
+			paragraph = append(paragraph, strings.Join(table[0], " "))
+		}
+		table = nil
+	}
+
+	for i, line := range lines {
+		text := line.text()
+
+		if level := headingLevel(line, body); level > 0 {
+			flushTable()
+			flushParagraph()
+			blocks = append(blocks, strings.Repeat("#", level)+" "+text)
+			continue
+		}
+
+		if len(line.Cells) >= 2 {
+			flushParagraph()
+			row := make([]string, len(line.Cells))
+			for j, cell := range line.Cells {
+				row[j] = cell.Text
+			}
+			table = append(table, row)
+			continue
+		}
+
+		flushTable()
+
+		// A vertical gap wider than the line spacing starts a new paragraph
+		if i > 0 && len(paragraph) > 0 {
+			gap := lines[i-1].Y - line.Y
+			if gap > 1.8*math.Max(line.Size, body) {
+				flushParagraph()
+			}
+		}
+
+		// Bullets start a new list item
+		if bullet, ok := trimBullet(text); ok {
+			flushParagraph()
+			text = "- " + bullet
+		}
+
+		paragraph = append(paragraph, text)
+	}
+
+	flushTable()
+	flushParagraph()
+
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+// headingLevel returns the Markdown heading level of a line, or 0 for body text
+func headingLevel(line textLine, body float64) int {
+	if len(line.Cells) != 1 || body <= 0 {
+		return 0
+	}
+
+	text := line.text()
+	if len([]rune(text)) > 120 || !hasLetter(text) {
+		return 0
+	}
+
+	switch ratio := line.Size / body; {
+	case ratio >= 1.6:
+		return 1
+	case ratio >= 1.25:
+		return 2
+	case line.Bold && isUpper(text):
+		return 3
+	}
+
+	return 0
+}
+
+// renderTable renders rows of cells as a Markdown table, using the first row as header
+func renderTable(rows [][]string) string {
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+
+	var b strings.Builder
+	for i, row := range rows {
+		b.WriteString("|")
+		for j := 0; j < columns; j++ {
+			cell := ""
+			if j < len(row) {
+				cell = strings.ReplaceAll(row[j], "|", `\|`)
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// joinParagraph joins wrapped lines, merging words hyphenated across lines
+func joinParagraph(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			if strings.HasSuffix(prev, "-") && startsLower(line) {
+				// "nomea-" + "ção" -> "nomeação"
+				str := b.String()
+				b.Reset()
+				b.WriteString(strings.TrimSuffix(str, "-"))
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// trimBullet removes a leading bullet glyph from a line
+func trimBullet(s string) (string, bool) {
+	for _, bullet := range []string{"•", "◦", "▪", "●", "–"} {
+		if strings.HasPrefix(s, bullet) {
+			return strings.TrimSpace(strings.TrimPrefix(s, bullet)), true
+		}
+	}
+	return s, false
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func isBoldFont(font string) bool {
+	font = strings.ToLower(font)
+	return strings.Contains(font, "bold") || strings.Contains(font, "black") || strings.Contains(font, "heavy")
+}
+
+func hasLetter(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func isUpper(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) && !unicode.IsUpper(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func startsLower(s string) bool {
+	for _, r := range s {
+		return unicode.IsLower(r)
+	}
+	return false
+}
@@ -0,0 +1,196 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// OCREngine recognizes the text of a single page of a PDF file
+type OCREngine interface {
+	// Recognize returns the text of a 1-based page and a confidence between 0 and 1
+	Recognize(ctx context.Context, pdfPath string, page int) (text string, confidence float64, err error)
+}
+
+// TesseractOCR renders pages with pdftoppm (poppler-utils) and recognizes them with tesseract
+type TesseractOCR struct {
+	Language string // Tesseract language model, e.g. "por"
+	DPI      int    // Rendering resolution
+}
+
+// NewTesseractOCR creates a TesseractOCR, failing when the binaries are not installed
+func NewTesseractOCR(language string) (*TesseractOCR, error) {
+	for _, bin := range []string{"pdftoppm", "tesseract"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			return nil, fmt.Errorf("%s not found in PATH: %w", bin, err)
+		}
+	}
+
+	if language == "" {
+		language = "por"
+	}
+
+	return &TesseractOCR{Language: language, DPI: 300}, nil
+}
+
+// Recognize renders the page to PNG and runs tesseract on it
+func (t *TesseractOCR) Recognize(ctx context.Context, pdfPath string, page int) (string, float64, error) {
+	dir, err := os.MkdirTemp("", "radar-oficial-ocr-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	imagePrefix := filepath.Join(dir, "page")
+	pageArg := strconv.Itoa(page)
+
+	render := exec.CommandContext(ctx, "pdftoppm",
+		"-f", pageArg, "-l", pageArg,
+		"-r", strconv.Itoa(t.DPI),
+		"-png", "-singlefile",
+		pdfPath, imagePrefix)
+	if output, err := render.CombinedOutput(); err != nil {
+		return "", 0, fmt.Errorf("pdftoppm failed: %v\noutput:\n%s", err, string(output))
+	}
+
+	var stderr bytes.Buffer
+	recognize := exec.CommandContext(ctx, "tesseract", imagePrefix+".png", "stdout", "-l", t.Language, "tsv")
+	recognize.Stderr = &stderr
+	output, err := recognize.Output()
+	if err != nil {
+		return "", 0, fmt.Errorf("tesseract failed: %v\noutput:\n%s", err, stderr.String())
+	}
+
+	text, confidence := parseTesseractTSV(output)
+	return text, confidence, nil
+}
+
+// parseTesseractTSV rebuilds the lines of text from tesseract's TSV output and returns
+// them with the average word confidence, scaled to 0..1
+func parseTesseractTSV(output []byte) (string, float64) {
+	var lines []string
+	var current []string
+	var lineKey string
+	var confidenceSum float64
+	var words int
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for first := true; scanner.Scan(); first = false {
+		if first {
+			continue // header
+		}
+
+		// level page_num block_num par_num line_num word_num left top width height conf text
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 12 || fields[0] != "5" {
+			continue
+		}
+
+		word := strings.TrimSpace(fields[11])
+		conf, err := strconv.ParseFloat(fields[10], 64)
+		if word == "" || err != nil || conf < 0 {
+			continue
+		}
+
+		key := strings.Join(fields[2:5], "-")
+		if key != lineKey && len(current) > 0 {
+			lines = append(lines, strings.Join(current, " "))
+			current = nil
+		}
+		lineKey = key
+
+		current = append(current, word)
+		confidenceSum += conf
+		words++
+	}
+
+	if len(current) > 0 {
+		lines = append(lines, strings.Join(current, " "))
+	}
+
+	if words == 0 {
+		return "", 0
+	}
+
+	return strings.Join(lines, "\n"), confidenceSum / float64(words) / 100
+}
+
+// OCRConverter wraps a Converter and sends pages with little or no extractable text,
+// typically scanned annexes, through an OCREngine
+type OCRConverter struct {
+	Converter Converter
+	Engine    OCREngine
+	MinChars  int // Pages with fewer letters and digits than this are OCR'd
+}
+
+// NewOCRConverter creates an OCRConverter
+func NewOCRConverter(converter Converter, engine OCREngine, minChars int) *OCRConverter {
+	return &OCRConverter{Converter: converter, Engine: engine, MinChars: minChars}
+}
+
+// Convert converts the PDF and replaces the text of near-empty pages with the OCR result
+func (c *OCRConverter) Convert(ctx context.Context, pdfContent []byte) ([]Page, error) {
+	pages, err := c.Converter.Convert(ctx, pdfContent)
+	if err != nil {
+		return nil, err
+	}
+
+	var f *os.File
+	for i := range pages {
+		if countTextChars(pages[i].Markdown) >= c.MinChars {
+			continue
+		}
+
+		// Only write the PDF to disk when a page actually needs OCR
+		if f == nil {
+			f, err = os.CreateTemp("", "radar-oficial-*.pdf")
+			if err != nil {
+				return nil, fmt.Errorf("failed to create temp file: %w", err)
+			}
+			defer os.Remove(f.Name())
+			defer f.Close()
+
+			if _, err := f.Write(pdfContent); err != nil {
+				return nil, fmt.Errorf("failed to write PDF content to temp file: %w", err)
+			}
+		}
+
+		text, confidence, err := c.Engine.Recognize(ctx, f.Name(), pages[i].Number)
+		if err != nil {
+			log.Printf("⚠️ OCR failed for page %d: %v", pages[i].Number, err)
+			continue
+		}
+
+		if countTextChars(text) <= countTextChars(pages[i].Markdown) {
+			continue
+		}
+
+		log.Printf("🔎 OCR'd page %d (confidence %.2f)", pages[i].Number, confidence)
+		pages[i].Markdown = text
+		pages[i].OCR = true
+		pages[i].OCRConfidence = confidence
+	}
+
+	return pages, nil
+}
+
+// countTextChars counts the letters and digits of a text, ignoring Markdown syntax
+func countTextChars(s string) int {
+	n := 0
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			n++
+		}
+	}
+	return n
+}
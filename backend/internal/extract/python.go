@@ -0,0 +1,105 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// pagePattern matches the files written by the script: page_1.md, page_01.md, page_001.md, etc.
+var pagePattern = regexp.MustCompile(`^page_(\d+)\.md$`)
+
+// PythonConverter converts PDFs by running scripts/split_and_convert_pdf.py (pymupdf4llm).
+// It requires a Python environment with the packages in scripts/requirements.txt.
+type PythonConverter struct {
+	Python string // Python interpreter, defaults to "python"
+	Script string // Path to split_and_convert_pdf.py
+}
+
+// NewPythonConverter creates a PythonConverter, using defaults for empty arguments
+func NewPythonConverter(python, script string) *PythonConverter {
+	if python == "" {
+		python = "python"
+	}
+	if script == "" {
+		script = "scripts/split_and_convert_pdf.py"
+	}
+
+	return &PythonConverter{Python: python, Script: script}
+}
+
+// Convert writes the PDF to a temp file, runs the script and reads back the Markdown pages
+func (c *PythonConverter) Convert(ctx context.Context, pdfContent []byte) ([]Page, error) {
+	f, err := os.CreateTemp("", "radar-oficial-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %v", err)
+	}
+
+	defer f.Close()
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(pdfContent)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to write PDF content to temp file: %v", err)
+	}
+
+	outputDir, err := os.MkdirTemp("", "radar-oficial-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	cmd := exec.CommandContext(ctx, c.Python, c.Script, f.Name(), outputDir)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"split/convert failed: %v\noutput:\n%s",
+			err, string(output),
+		)
+	}
+	// on success, output contains anything the script printed
+	log.Printf("split script output:\n%s", string(output))
+
+	return readPagesDir(outputDir)
+}
+
+// readPagesDir reads the page_N.md files of a directory, ordered by page number
+func readPagesDir(dir string) ([]Page, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading directory: %v", err)
+	}
+
+	var pages []Page
+
+	for _, entry := range entries {
+		// Check if the file matches our pattern
+		matches := pagePattern.FindStringSubmatch(entry.Name())
+		if len(matches) < 2 {
+			continue // Skip files that don't match the pattern
+		}
+
+		// Extract the page number
+		pageNum, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing page number from %s: %v", entry.Name(), err)
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("Error reading file %s: %v", entry.Name(), err)
+		}
+
+		pages = append(pages, Page{Number: pageNum, Markdown: string(content)})
+	}
+
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Number < pages[j].Number })
+
+	return pages, nil
+}
@@ -0,0 +1,227 @@
+package identifiers
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Kinds of identifiers extracted from gazette text
+const (
+	KindProcesso = "processo" // SEI/NUP process numbers, e.g. 00012.036017/2024-41
+	KindCNPJ     = "cnpj"
+	KindCPF      = "cpf" // Stored masked, as required by the LGPD
+	KindContrato = "contrato"
+	KindPregao   = "pregao"
+	KindValor    = "valor" // Monetary values in reais
+)
+
+// Kinds lists every supported kind
+var Kinds = []string{KindProcesso, KindCNPJ, KindCPF, KindContrato, KindPregao, KindValor}
+
+// Found is an identifier found in a text
+type Found struct {
+	Kind       string
+	Value      string // As published, or masked for CPFs
+	Normalized string
+	Amount     *float64
+}
+
+var (
+	seiRegexp      = regexp.MustCompile(`\b\d{5}\.\d{6}/\d{4}-\d{2}\b`)
+	processoRegexp = regexp.MustCompile(`(?i)\bprocesso(?:\s+(?:administrativo|sei|n[º°o.]?))*\s*[:.º°]?\s*(\d[\d./-]{4,}\d)`)
+	cnpjRegexp     = regexp.MustCompile(`\b\d{2}\.\d{3}\.\d{3}/\d{4}-\d{2}\b|(?i)\bcnpj\s*(?:n[º°o.]?)?\s*[:.]?\s*(\d{14})\b`)
+	cpfRegexp      = regexp.MustCompile(`(?:\b\d{3}|\*{3})\.\d{3}\.\d{3}-(?:\d{2}\b|\*{2})`)
+	contratoRegexp = regexp.MustCompile(`(?i)\bcontrato(?:\s+administrativo)?\s*(?:n[º°o.]?)?\s*[:.º°]?\s*(\d{1,6}/\d{4})\b`)
+	pregaoRegexp   = regexp.MustCompile(`(?i)\bpreg[aã]o(?:\s+(?:eletr[oô]nico|presencial))?(?:\s+srp)?\s*(?:n[º°o.]?)?\s*[:.º°]?\s*(\d{1,6}/\d{4})\b`)
+	valorRegexp    = regexp.MustCompile(`R\$\s*(\d{1,3}(?:\.\d{3})*,\d{2}|\d+,\d{2})`)
+)
+
+// Extract finds the identifiers cited in a text, without duplicates. CPFs and CNPJs
+// whose verification digits do not match are left out.
+func Extract(text string) []Found {
+	var found []Found
+	seen := map[string]bool{}
+
+	add := func(f Found) {
+		key := f.Kind + ":" + f.Normalized
+		if f.Normalized == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		found = append(found, f)
+	}
+
+	for _, m := range seiRegexp.FindAllString(text, -1) {
+		add(Found{Kind: KindProcesso, Value: m, Normalized: digits(m)})
+	}
+	for _, m := range processoRegexp.FindAllStringSubmatch(text, -1) {
+		if n := digits(m[1]); len(n) >= 6 {
+			add(Found{Kind: KindProcesso, Value: m[1], Normalized: n})
+		}
+	}
+
+	for _, loc := range cnpjRegexp.FindAllStringSubmatchIndex(text, -1) {
+		if continuesNumber(text, loc[0]) {
+			continue
+		}
+		value := text[loc[0]:loc[1]]
+		if loc[2] >= 0 {
+			value = text[loc[2]:loc[3]]
+		}
+		if n := digits(value); validCNPJ(n) {
+			add(Found{Kind: KindCNPJ, Value: value, Normalized: n})
+		}
+	}
+
+	for _, loc := range cpfRegexp.FindAllStringIndex(text, -1) {
+		if continuesNumber(text, loc[0]) {
+			continue
+		}
+		m := text[loc[0]:loc[1]]
+		// Masked CPFs cannot be verified
+		if n := digits(m); len(n) == 11 && !validCPF(n) {
+			continue
+		}
+		masked := MaskCPF(m)
+		add(Found{Kind: KindCPF, Value: masked, Normalized: normalizeCPF(masked)})
+	}
+
+	for _, m := range contratoRegexp.FindAllStringSubmatch(text, -1) {
+		add(Found{Kind: KindContrato, Value: m[1], Normalized: normalizeNumberYear(m[1])})
+	}
+	for _, m := range pregaoRegexp.FindAllStringSubmatch(text, -1) {
+		add(Found{Kind: KindPregao, Value: m[1], Normalized: normalizeNumberYear(m[1])})
+	}
+
+	for _, m := range valorRegexp.FindAllStringSubmatch(text, -1) {
+		normalized := strings.ReplaceAll(strings.ReplaceAll(m[1], ".", ""), ",", ".")
+		amount, err := strconv.ParseFloat(normalized, 64)
+		if err != nil {
+			continue
+		}
+		add(Found{Kind: KindValor, Value: "R$ " + m[1], Normalized: normalized, Amount: &amount})
+	}
+
+	return found
+}
+
+// Normalize returns the canonical form of a value of the given kind, as stored in the
+// normalized column, so that lookups match regardless of punctuation
+func Normalize(kind, value string) string {
+	value = strings.TrimSpace(value)
+
+	switch kind {
+	case KindProcesso, KindCNPJ:
+		return digits(value)
+	case KindCPF:
+		return normalizeCPF(MaskCPF(value))
+	case KindContrato, KindPregao:
+		return normalizeNumberYear(value)
+	case KindValor:
+		value = strings.TrimSpace(strings.TrimPrefix(value, "R$"))
+		if strings.Contains(value, ",") {
+			value = strings.ReplaceAll(strings.ReplaceAll(value, ".", ""), ",", ".")
+		}
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return ""
+		}
+		return strconv.FormatFloat(amount, 'f', 2, 64)
+	}
+
+	return ""
+}
+
+// MaskCPF hides the first three and the last two digits of a CPF: ***.456.789-**
+func MaskCPF(cpf string) string {
+	d := digits(cpf)
+	switch len(d) {
+	case 11:
+		d = d[3:9]
+	case 6:
+		// Already masked
+	default:
+		return ""
+	}
+	return "***." + d[:3] + "." + d[3:] + "-**"
+}
+
+func normalizeCPF(masked string) string {
+	if masked == "" {
+		return ""
+	}
+	return "***" + digits(masked) + "**"
+}
+
+// normalizeNumberYear turns "012/2025" into "12/2025"
+func normalizeNumberYear(s string) string {
+	number, year, ok := strings.Cut(s, "/")
+	if !ok {
+		return ""
+	}
+
+	n, err := strconv.Atoi(digits(number))
+	if err != nil {
+		return ""
+	}
+
+	return strconv.Itoa(n) + "/" + digits(year)
+}
+
+func digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// continuesNumber reports whether a match at start is the end of a longer number, such
+// as "234.567.890-12" in "1.234.567.890-12"
+func continuesNumber(text string, start int) bool {
+	return start >= 2 && text[start-1] == '.' && text[start-2] >= '0' && text[start-2] <= '9'
+}
+
+// validCPF checks the two verification digits of a CPF
+func validCPF(cpf string) bool {
+	if len(cpf) != 11 || strings.Count(cpf, cpf[:1]) == 11 {
+		return false
+	}
+
+	check := func(n int) int {
+		sum := 0
+		for i := 0; i < n; i++ {
+			sum += int(cpf[i]-'0') * (n + 1 - i)
+		}
+		if r := sum % 11; r >= 2 {
+			return 11 - r
+		}
+		return 0
+	}
+
+	return check(9) == int(cpf[9]-'0') && check(10) == int(cpf[10]-'0')
+}
+
+// validCNPJ checks the two verification digits of a CNPJ
+func validCNPJ(cnpj string) bool {
+	if len(cnpj) != 14 || strings.Count(cnpj, cnpj[:1]) == 14 {
+		return false
+	}
+
+	check := func(n int) int {
+		weights := []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}[13-n:]
+		sum := 0
+		for i := 0; i < n; i++ {
+			sum += int(cnpj[i]-'0') * weights[i]
+		}
+		if r := sum % 11; r >= 2 {
+			return 11 - r
+		}
+		return 0
+	}
+
+	return check(12) == int(cnpj[12]-'0') && check(13) == int(cnpj[13]-'0')
+}
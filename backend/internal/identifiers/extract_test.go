@@ -0,0 +1,104 @@
+package identifiers
+
+import (
+	"reflect"
+	"testing"
+)
+
+// found lists the kind and normalized value of the identifiers found in a text
+func found(text string) [][2]string {
+	var got [][2]string
+	for _, f := range Extract(text) {
+		got = append(got, [2]string{f.Kind, f.Normalized})
+	}
+	return got
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		text string
+		want [][2]string
+	}{
+		// Documents
+		{"CONTRATADA: ACME LTDA, CNPJ 11.222.333/0001-81.", [][2]string{{KindCNPJ, "11222333000181"}}},
+		{"inscrita no CNPJ nº 11222333000181", [][2]string{{KindCNPJ, "11222333000181"}}},
+		{"CNPJ 11.222.333/0001-82, dígito verificador errado", nil},
+		{"CNPJ 00.000.000/0000-00", nil},
+		{"MARIA DA SILVA, CPF 529.982.247-25, matrícula 12345", [][2]string{{KindCPF, "***982247**"}}},
+		{"MARIA DA SILVA, CPF ***.982.247-**", [][2]string{{KindCPF, "***982247**"}}},
+		{"CPF 529.982.247-24, dígito verificador errado", nil},
+		{"CPF 111.111.111-11", nil},
+
+		// Processes, contracts and pregões
+		{"Processo SEI nº 00012.036017/2024-41", [][2]string{{KindProcesso, "00012036017202441"}}},
+		{"PROCESSO ADMINISTRATIVO Nº 2025.01.123", [][2]string{{KindProcesso, "202501123"}}},
+		{"EXTRATO DO CONTRATO Nº 012/2025", [][2]string{{KindContrato, "12/2025"}}},
+		{"PREGÃO ELETRÔNICO SRP Nº 07/2025", [][2]string{{KindPregao, "7/2025"}}},
+		{"Valor global: R$ 1.234.567,89", [][2]string{{KindValor, "1234567.89"}}},
+
+		// Ordinary numbers
+		{"processo seletivo simplificado nº 01/2025", nil},
+		{"Processo nº 123", nil},
+		{"protocolo 1.234.567.890-12 do atendimento", nil},
+		{"telefone (86) 3216-1234, CEP 64.000-060", nil},
+		{"Lei nº 8.666/1993, art. 24, inciso II", nil},
+		{"matrícula 001.234.567-8", nil},
+	}
+
+	for _, tt := range tests {
+		if got := found(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Extract(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestExtractMasksCPF(t *testing.T) {
+	for _, f := range Extract("nomeia MARIA DA SILVA, CPF 529.982.247-25") {
+		if f.Kind == KindCPF && f.Value != "***.982.247-**" {
+			t.Errorf("CPF value = %q, want it masked", f.Value)
+		}
+	}
+}
+
+func TestMaskCPF(t *testing.T) {
+	tests := []struct {
+		cpf  string
+		want string
+	}{
+		{"529.982.247-25", "***.982.247-**"},
+		{"52998224725", "***.982.247-**"},
+		{"***.982.247-**", "***.982.247-**"},
+		{"982.247", "***.982.247-**"},
+		{"5299822472", ""},
+	}
+
+	for _, tt := range tests {
+		if got := MaskCPF(tt.cpf); got != tt.want {
+			t.Errorf("MaskCPF(%q) = %q, want %q", tt.cpf, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		kind  string
+		value string
+		want  string
+	}{
+		{KindCNPJ, "11.222.333/0001-81", "11222333000181"},
+		{KindCPF, "529.982.247-25", "***982247**"},
+		{KindCPF, "***.982.247-**", "***982247**"},
+		{KindProcesso, "00012.036017/2024-41", "00012036017202441"},
+		{KindContrato, "012/2025", "12/2025"},
+		{KindContrato, "12", ""},
+		{KindValor, "R$ 1.234,50", "1234.50"},
+		{KindValor, "1234.5", "1234.50"},
+		{KindValor, "mil reais", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.kind, tt.value); got != tt.want {
+			t.Errorf("Normalize(%s, %q) = %q, want %q", tt.kind, tt.value, got, tt.want)
+		}
+	}
+}
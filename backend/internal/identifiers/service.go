@@ -0,0 +1,147 @@
+package identifiers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"radaroficial.app/internal/model"
+)
+
+// maxLookupResults bounds the number of acts returned by an exact lookup
+const maxLookupResults = 20
+
+type IdentifierService struct {
+	DB *pgxpool.Pool
+}
+
+func NewIdentifierService(db *pgxpool.Pool) *IdentifierService {
+	return &IdentifierService{DB: db}
+}
+
+// Match is an ato citing an identifier, with the diario it was published in
+type Match struct {
+	Identifier        model.Identifier `json:"identifier"`
+	AtoTitle          string           `json:"atoTitle"`
+	ActType           string           `json:"actType"`
+	Orgao             *string          `json:"orgao,omitempty"`
+	Content           string           `json:"content"`
+	PageStart         int              `json:"pageStart"`
+	PageEnd           int              `json:"pageEnd"`
+	DiarioDescription *string          `json:"diarioDescription,omitempty"`
+	EditionNumber     *int             `json:"editionNumber,omitempty"`
+	PublishedAt       *time.Time       `json:"publishedAt,omitempty"`
+	SourceURL         string           `json:"sourceUrl"`
+	InstitutionID     int              `json:"institutionId"`
+	Institution       string           `json:"institution"` // Name of the institution
+	State             string           `json:"state"`
+}
+
+// ExtractForAtos extracts the identifiers cited by each ato and stores them, replacing
+// those previously extracted from the same atos
+func (s *IdentifierService) ExtractForAtos(ctx context.Context, atos []model.Ato) (int, error) {
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO identifiers (ato_id, diario_id, kind, value, normalized, amount, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (ato_id, kind, normalized) DO NOTHING;
+	`
+
+	count := 0
+	for _, ato := range atos {
+		if _, err := tx.Exec(ctx, `DELETE FROM identifiers WHERE ato_id = $1`, ato.ID); err != nil {
+			return 0, fmt.Errorf("failed to delete identifiers of ato %d: %w", ato.ID, err)
+		}
+
+		for _, f := range Extract(ato.Title + "\n" + ato.Content) {
+			if _, err := tx.Exec(ctx, query, ato.ID, ato.DiarioID, f.Kind, f.Value, f.Normalized, f.Amount); err != nil {
+				return 0, fmt.Errorf("failed to insert %s %s: %w", f.Kind, f.Value, err)
+			}
+			count++
+		}
+	}
+
+	return count, tx.Commit(ctx)
+}
+
+// Lookup returns the atos citing exactly the given identifier, most recent first
+func (s *IdentifierService) Lookup(ctx context.Context, kind, value string) ([]Match, error) {
+	normalized := Normalize(kind, value)
+	if normalized == "" {
+		return nil, fmt.Errorf("invalid %s: %q", kind, value)
+	}
+
+	return s.lookup(ctx, []string{kind}, []string{normalized})
+}
+
+// LookupText extracts the identifiers cited in a free text, such as a chat message,
+// and returns the atos citing any of them
+func (s *IdentifierService) LookupText(ctx context.Context, text string) ([]Match, error) {
+	var kinds, normalized []string
+	for _, f := range Extract(text) {
+		// Amounts alone are too ambiguous to pin down an ato
+		if f.Kind == KindValor {
+			continue
+		}
+		kinds = append(kinds, f.Kind)
+		normalized = append(normalized, f.Normalized)
+	}
+
+	if len(kinds) == 0 {
+		return nil, nil
+	}
+
+	return s.lookup(ctx, kinds, normalized)
+}
+
+func (s *IdentifierService) lookup(ctx context.Context, kinds, normalized []string) ([]Match, error) {
+	query := `
+		SELECT
+			i.id, i.ato_id, i.diario_id, i.kind, i.value, i.normalized, i.amount, i.created_at,
+			a.title, a.act_type, a.orgao, a.content, a.page_start, a.page_end,
+			d.description, d.edition_number, d.published_at, COALESCE(d.source_url, ''),
+			inst.id, inst.name, inst.state
+		FROM identifiers i
+		JOIN unnest($1::text[], $2::text[]) AS q(kind, normalized)
+			ON i.kind = q.kind AND i.normalized = q.normalized
+		JOIN atos a ON a.id = i.ato_id
+		JOIN diarios d ON d.id = i.diario_id
+		JOIN institutions inst ON inst.id = d.institution_id
+		ORDER BY d.published_at DESC NULLS LAST, a.seq ASC
+		LIMIT $3;
+	`
+
+	rows, err := s.DB.Query(ctx, query, kinds, normalized, maxLookupResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		var m Match
+		i := &m.Identifier
+		err := rows.Scan(
+			&i.ID, &i.AtoID, &i.DiarioID, &i.Kind, &i.Value, &i.Normalized, &i.Amount, &i.CreatedAt,
+			&m.AtoTitle, &m.ActType, &m.Orgao, &m.Content, &m.PageStart, &m.PageEnd,
+			&m.DiarioDescription, &m.EditionNumber, &m.PublishedAt, &m.SourceURL,
+			&m.InstitutionID, &m.Institution, &m.State,
+		)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
@@ -2,8 +2,12 @@ package institutions
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"radaroficial.app/internal/model"
 )
 
 type InstitutionService struct {
@@ -41,3 +45,117 @@ func (s *InstitutionService) GetStates(ctx context.Context) ([]string, error) {
 
 	return states, nil
 }
+
+// GetBySlug returns the institution identified by slug
+func (s *InstitutionService) GetBySlug(ctx context.Context, slug string) (*model.Institution, error) {
+	query := `
+		SELECT id, name, slug, type, state, city, source_url, COALESCE(active, FALSE), created_at, updated_at
+		FROM institutions
+		WHERE slug = $1
+	`
+
+	i := &model.Institution{}
+	err := s.DB.QueryRow(ctx, query, slug).Scan(
+		&i.ID, &i.Name, &i.Slug, &i.Type, &i.State, &i.City,
+		&i.SourceUrl, &i.Active, &i.CreatedAt, &i.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get institution %s: %w", slug, err)
+	}
+
+	return i, nil
+}
+
+// ListActive returns the active institutions, optionally of a single state
+func (s *InstitutionService) ListActive(ctx context.Context, state string) ([]model.Institution, error) {
+	query := `
+		SELECT id, name, slug, type, state, city, source_url, COALESCE(active, FALSE), created_at, updated_at
+		FROM institutions
+		WHERE active = true AND ($1 = '' OR state = $1)
+		ORDER BY id
+	`
+
+	rows, err := s.DB.Query(ctx, query, state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list institutions: %w", err)
+	}
+	defer rows.Close()
+
+	var institutions []model.Institution
+	for rows.Next() {
+		var i model.Institution
+		if err := rows.Scan(
+			&i.ID, &i.Name, &i.Slug, &i.Type, &i.State, &i.City,
+			&i.SourceUrl, &i.Active, &i.CreatedAt, &i.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan institution: %w", err)
+		}
+		institutions = append(institutions, i)
+	}
+
+	return institutions, rows.Err()
+}
+
+// ListFilter selects institutions in List. Empty fields match every institution.
+type ListFilter struct {
+	Type   string
+	State  string
+	City   string // Compared ignoring case
+	Active *bool
+}
+
+// List returns the institutions matching filter, ordered by state and name
+func (s *InstitutionService) List(ctx context.Context, filter ListFilter) ([]model.Institution, error) {
+	query := `
+		SELECT id, name, slug, type, state, city, source_url, COALESCE(active, FALSE), created_at, updated_at
+		FROM institutions
+		WHERE ($1 = '' OR type = $1)
+			AND ($2 = '' OR state = $2)
+			AND ($3 = '' OR lower(city) = lower($3))
+			AND ($4::boolean IS NULL OR COALESCE(active, FALSE) = $4)
+		ORDER BY state, name
+	`
+
+	rows, err := s.DB.Query(ctx, query, filter.Type, filter.State, filter.City, filter.Active)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list institutions: %w", err)
+	}
+	defer rows.Close()
+
+	institutions := []model.Institution{}
+	for rows.Next() {
+		var i model.Institution
+		if err := rows.Scan(
+			&i.ID, &i.Name, &i.Slug, &i.Type, &i.State, &i.City,
+			&i.SourceUrl, &i.Active, &i.CreatedAt, &i.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan institution: %w", err)
+		}
+		institutions = append(institutions, i)
+	}
+
+	return institutions, rows.Err()
+}
+
+// GetByID returns the institution with the given ID, or nil when there is none
+func (s *InstitutionService) GetByID(ctx context.Context, id int) (*model.Institution, error) {
+	query := `
+		SELECT id, name, slug, type, state, city, source_url, COALESCE(active, FALSE), created_at, updated_at
+		FROM institutions
+		WHERE id = $1
+	`
+
+	i := &model.Institution{}
+	err := s.DB.QueryRow(ctx, query, id).Scan(
+		&i.ID, &i.Name, &i.Slug, &i.Type, &i.State, &i.City,
+		&i.SourceUrl, &i.Active, &i.CreatedAt, &i.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get institution %d: %w", id, err)
+	}
+
+	return i, nil
+}
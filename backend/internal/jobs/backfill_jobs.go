@@ -0,0 +1,360 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/riverdriver/riverpgxv5"
+	"radaroficial.app/internal/diarios"
+	"radaroficial.app/internal/institutions"
+	"radaroficial.app/internal/model"
+)
+
+// BackfillDiariosArgs contains arguments for the job
+type BackfillDiariosArgs struct {
+	Slug      string `json:"slug"`       // Slug of the institution to backfill
+	StartDate string `json:"start_date"` // First day in YYYY-MM-DD format
+	EndDate   string `json:"end_date"`   // Last day in YYYY-MM-DD format
+}
+
+// Kind returns the kind of job
+func (BackfillDiariosArgs) Kind() string { return "backfill_diarios" }
+
+// BackfillDiariosWorker fans out one fetch job per day of a date range, or starts the
+// edition walk of a NumberedSource
+type BackfillDiariosWorker struct {
+	// Embed worker defaults
+	river.WorkerDefaults[BackfillDiariosArgs]
+
+	// Add dependencies
+	BackfillService    *diarios.BackfillService
+	InstitutionService *institutions.InstitutionService
+}
+
+// NewBackfillDiariosWorker creates a new BackfillDiariosWorker
+func NewBackfillDiariosWorker(backfillService *diarios.BackfillService, institutionService *institutions.InstitutionService) *BackfillDiariosWorker {
+	return &BackfillDiariosWorker{
+		BackfillService:    backfillService,
+		InstitutionService: institutionService,
+	}
+}
+
+// Work enqueues the fetch job of every day not yet enqueued. Progress is stored in the
+// backfills table, so a retried or restarted job resumes from the first pending day, and
+// enqueues again the days whose fetch did not succeed. Sources numbering their editions
+// are walked back by edition number instead.
+func (w *BackfillDiariosWorker) Work(ctx context.Context, job *river.Job[BackfillDiariosArgs]) error {
+	src, ok := diarios.GetSource(job.Args.Slug)
+	if !ok {
+		return river.JobCancel(fmt.Errorf("no diario source registered for %s", job.Args.Slug))
+	}
+
+	start, end, err := parseDateRange(job.Args.StartDate, job.Args.EndDate)
+	if err != nil {
+		return river.JobCancel(err)
+	}
+
+	institution, err := w.InstitutionService.GetBySlug(ctx, job.Args.Slug)
+	if err != nil {
+		return err
+	}
+
+	backfill, err := w.BackfillService.GetOrCreate(ctx, institution.ID, start, end)
+	if err != nil {
+		return fmt.Errorf("failed to load backfill: %w", err)
+	}
+
+	client := river.ClientFromContext[pgx.Tx](ctx)
+
+	if _, ok := src.(diarios.NumberedSource); ok {
+		_, err := client.Insert(ctx, BackfillEditionsArgs{Slug: job.Args.Slug, BackfillID: backfill.ID}, &river.InsertOpts{
+			Queue:      "backfill",
+			Priority:   4,
+			UniqueOpts: pendingUniqueOpts,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to enqueue edition walk of %s: %w", job.Args.Slug, err)
+		}
+
+		log.Printf("✅ Backfill of %s from %s to %s walks back by edition number", job.Args.Slug, job.Args.StartDate, job.Args.EndDate)
+		return nil
+	}
+
+	log.Printf("🔄 Backfilling %s from %s to %s, resuming at %s (ID: %d)", job.Args.Slug,
+		job.Args.StartDate, job.Args.EndDate, backfill.NextDate.Format("2006-01-02"), job.ID)
+
+	// Days whose fetch was discarded, the ones still pending are deduplicated
+	unfetched, err := w.BackfillService.ListUnfetchedDays(ctx, backfill.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list unfetched days: %w", err)
+	}
+
+	for _, day := range unfetched {
+		if _, err := client.Insert(ctx, fetchDayArgs(backfill.ID, job.Args.Slug, day), backfillDayInsertOpts()); err != nil {
+			return fmt.Errorf("failed to enqueue %s for %s: %w", job.Args.Slug, day.Format("2006-01-02"), err)
+		}
+	}
+
+	for day := backfill.NextDate; !day.After(backfill.EndDate); day = day.AddDate(0, 0, 1) {
+		if err := w.enqueueDay(ctx, client, backfill.ID, job.Args.Slug, day); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("✅ Backfill of %s from %s to %s fully enqueued, it completes once every day is fetched",
+		job.Args.Slug, job.Args.StartDate, job.Args.EndDate)
+	return nil
+}
+
+// enqueueDay inserts the fetch job of a day and advances the backfill in one transaction
+func (w *BackfillDiariosWorker) enqueueDay(ctx context.Context, client *river.Client[pgx.Tx], backfillID int, slug string, day time.Time) error {
+	tx, err := w.BackfillService.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := client.InsertTx(ctx, tx, fetchDayArgs(backfillID, slug, day), backfillDayInsertOpts()); err != nil {
+		return fmt.Errorf("failed to enqueue %s for %s: %w", slug, day.Format("2006-01-02"), err)
+	}
+
+	if err := w.BackfillService.Advance(ctx, tx, backfillID, day); err != nil {
+		return fmt.Errorf("failed to record backfill progress: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+func fetchDayArgs(backfillID int, slug string, day time.Time) FetchDiariosArgs {
+	return FetchDiariosArgs{Slug: slug, Date: day.Format("2006-01-02"), BackfillID: backfillID}
+}
+
+func backfillDayInsertOpts() *river.InsertOpts {
+	return &river.InsertOpts{
+		Queue:      "backfill",
+		Priority:   4, // Lowest priority, backfills must not delay the periodic jobs
+		UniqueOpts: pendingUniqueOpts,
+	}
+}
+
+// MaxRetries defines max attempts for this job
+func (w *BackfillDiariosWorker) MaxRetries(job *river.Job[BackfillDiariosArgs]) int {
+	return 5
+}
+
+// Timeout sets the maximum execution time for this job, as declared by the source, as
+// ranges of several years enqueue thousands of days
+func (w *BackfillDiariosWorker) Timeout(job *river.Job[BackfillDiariosArgs]) time.Duration {
+	if src, ok := diarios.GetSource(job.Args.Slug); ok {
+		return src.Metadata().Timeout
+	}
+	return 10 * time.Minute
+}
+
+// ScheduleBackfillJob schedules a job to backfill the diarios of a source over a date range
+func ScheduleBackfillJob(ctx context.Context, client *river.Client[pgx.Tx], slug, startDate, endDate string) error {
+	if _, ok := diarios.GetSource(slug); !ok {
+		return fmt.Errorf("no diario source registered for %s", slug)
+	}
+
+	if _, _, err := parseDateRange(startDate, endDate); err != nil {
+		return err
+	}
+
+	_, err := client.Insert(ctx, BackfillDiariosArgs{
+		Slug:      slug,
+		StartDate: startDate,
+		EndDate:   endDate,
+	}, &river.InsertOpts{
+		Queue:      "default",
+		Priority:   1,
+		UniqueOpts: pendingUniqueOpts, // Scheduling it again resumes it
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to schedule %s backfill: %w", slug, err)
+	}
+
+	log.Printf("✅ Scheduled backfill of %s from %s to %s", slug, startDate, endDate)
+	return nil
+}
+
+// parseDateRange parses and validates a YYYY-MM-DD date range
+func parseDateRange(startDate, endDate string) (time.Time, time.Time, error) {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date %s, expected YYYY-MM-DD: %w", startDate, err)
+	}
+
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date %s, expected YYYY-MM-DD: %w", endDate, err)
+	}
+
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end date %s is before start date %s", endDate, startDate)
+	}
+
+	return start, end, nil
+}
+
+// BackfillEditionsArgs contains arguments for the job
+type BackfillEditionsArgs struct {
+	Slug       string `json:"slug"`             // Slug of the institution, its source must number its editions
+	BackfillID int    `json:"backfill_id"`      // Backfill giving the date range
+	Number     int    `json:"number,omitempty"` // Next edition to fetch, the last one before the range ends if 0
+}
+
+// Kind returns the kind of job
+func (BackfillEditionsArgs) Kind() string { return "backfill_editions" }
+
+// BackfillEditionsWorker backfills a NumberedSource by walking back one edition per job,
+// from the end of the date range until an edition published before its start. Loading
+// an edition by number costs one page, where discovering a day walks back from the
+// edição atual.
+type BackfillEditionsWorker struct {
+	// Embed worker defaults
+	river.WorkerDefaults[BackfillEditionsArgs]
+
+	// Add dependencies
+	Ingester           *diarios.Ingester
+	InstitutionService *institutions.InstitutionService
+	BackfillService    *diarios.BackfillService
+}
+
+// NewBackfillEditionsWorker creates a new BackfillEditionsWorker
+func NewBackfillEditionsWorker(ingester *diarios.Ingester, institutionService *institutions.InstitutionService, backfillService *diarios.BackfillService) *BackfillEditionsWorker {
+	return &BackfillEditionsWorker{
+		Ingester:           ingester,
+		InstitutionService: institutionService,
+		BackfillService:    backfillService,
+	}
+}
+
+// Work registers the next edition of the walk, if in range, and enqueues the one before
+func (w *BackfillEditionsWorker) Work(ctx context.Context, job *river.Job[BackfillEditionsArgs]) error {
+	src, err := numberedSource(job.Args.Slug)
+	if err != nil {
+		return river.JobCancel(err)
+	}
+
+	backfill, err := w.BackfillService.GetByID(ctx, job.Args.BackfillID)
+	if err != nil {
+		return fmt.Errorf("failed to load backfill: %w", err)
+	}
+	if backfill == nil {
+		return river.JobCancel(fmt.Errorf("backfill %d not found", job.Args.BackfillID))
+	}
+
+	institution, err := w.InstitutionService.GetBySlug(ctx, job.Args.Slug)
+	if err != nil {
+		return err
+	}
+
+	number := job.Args.Number
+	if number == 0 {
+		if number, err = w.lastNumber(ctx, src, institution.ID, backfill.EndDate); err != nil {
+			return err
+		}
+	}
+
+	// Stored editions are skipped without loading their page
+	for ; number >= 1; number-- {
+		stored, err := w.Ingester.Service.GetByEditionNumber(ctx, institution.ID, number)
+		if err != nil {
+			return fmt.Errorf("failed to load edição %d: %w", number, err)
+		}
+		if stored == nil {
+			break
+		}
+		if stored.PublishedAt != nil && stored.PublishedAt.Before(backfill.StartDate) {
+			return w.complete(ctx, backfill, job.Args.Slug)
+		}
+	}
+
+	if number < 1 {
+		return w.complete(ctx, backfill, job.Args.Slug)
+	}
+
+	edition, err := src.FetchEdition(ctx, number)
+	if err != nil {
+		return fmt.Errorf("failed to fetch edição %d: %w", number, err)
+	}
+
+	if edition.PublishedAt.Before(backfill.StartDate) {
+		return w.complete(ctx, backfill, job.Args.Slug)
+	}
+
+	if !edition.PublishedAt.After(backfill.EndDate) {
+		if _, err := registerEditions(ctx, w.Ingester, job.JobRow, institution, []diarios.Edition{edition}); err != nil {
+			return err
+		}
+	}
+
+	if number == 1 {
+		return w.complete(ctx, backfill, job.Args.Slug)
+	}
+
+	// The previous edition is enqueued as this job completes, so a retry does not fork the walk
+	tx, err := w.BackfillService.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	client := river.ClientFromContext[pgx.Tx](ctx)
+	next := BackfillEditionsArgs{Slug: job.Args.Slug, BackfillID: backfill.ID, Number: number - 1}
+	if _, err := client.InsertTx(ctx, tx, next, &river.InsertOpts{
+		Queue:      job.Queue,
+		Priority:   job.Priority,
+		UniqueOpts: pendingUniqueOpts,
+	}); err != nil {
+		return fmt.Errorf("failed to enqueue edição %d: %w", next.Number, err)
+	}
+
+	if _, err := river.JobCompleteTx[*riverpgxv5.Driver](ctx, tx, job); err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// lastNumber returns the number of the last edition published before the backfill ends:
+// the one before the first stored edition published after it, or the edição atual
+func (w *BackfillEditionsWorker) lastNumber(ctx context.Context, src diarios.NumberedSource, institutionID int, end time.Time) (int, error) {
+	first, err := w.Ingester.Service.FirstEditionSince(ctx, institutionID, end.AddDate(0, 0, 1))
+	if err != nil {
+		return 0, fmt.Errorf("failed to find the first edition after the backfill: %w", err)
+	}
+	if first > 0 {
+		return first - 1, nil
+	}
+
+	latest, err := src.LatestEdition(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch latest edition: %w", err)
+	}
+	return latest.Number, nil
+}
+
+func (w *BackfillEditionsWorker) complete(ctx context.Context, backfill *model.Backfill, slug string) error {
+	if err := w.BackfillService.MarkCompleted(ctx, backfill.ID); err != nil {
+		return fmt.Errorf("failed to complete backfill: %w", err)
+	}
+
+	log.Printf("✅ Backfill of %s from %s to %s completed", slug,
+		backfill.StartDate.Format("2006-01-02"), backfill.EndDate.Format("2006-01-02"))
+	return nil
+}
+
+// Timeout sets the maximum execution time for this job, as declared by the source
+func (w *BackfillEditionsWorker) Timeout(job *river.Job[BackfillEditionsArgs]) time.Duration {
+	if src, ok := diarios.GetSource(job.Args.Slug); ok {
+		return src.Metadata().Timeout
+	}
+	return 10 * time.Minute
+}
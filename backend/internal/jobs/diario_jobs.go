@@ -9,80 +9,63 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/riverqueue/river"
 	"radaroficial.app/internal/diarios"
-	"radaroficial.app/internal/storage"
+	"radaroficial.app/internal/institutions"
 )
 
-// DiarioDosMunicipiosArgs contains arguments for the job
-type DiarioDosMunicipiosArgs struct{}
-
-// Kind returns the kind of job
-func (DiarioDosMunicipiosArgs) Kind() string { return "fetch_diario_dos_municipios" }
-
-// DiarioWorker handles diario-related jobs
-type DiarioWorker struct {
-	// Embed worker defaults
-	river.WorkerDefaults[DiarioDosMunicipiosArgs]
-
-	// Add dependencies
-	DiarioService *diarios.DiarioService
-	Uploader      *storage.SpacesUploader
-}
-
-// GovernoPiauiArgs contains arguments for the job
-type GovernoPiauiArgs struct {
-	Date string `json:"date"` // Optional date in YYYY-MM-DD format
+// FetchDiariosArgs contains arguments for the job
+type FetchDiariosArgs struct {
+	Slug       string `json:"slug"`                  // Slug of the institution whose source should be fetched
+	Date       string `json:"date,omitempty"`        // Optional date in YYYY-MM-DD format
+	BackfillID int    `json:"backfill_id,omitempty"` // Backfill the date belongs to, if any
 }
 
 // Kind returns the kind of job
-func (GovernoPiauiArgs) Kind() string { return "fetch_governo_piaui" }
+func (FetchDiariosArgs) Kind() string { return "fetch_diarios" }
 
-// GovernoPiauiWorker handles diario-related jobs for Governo do Piauí
-type GovernoPiauiWorker struct {
+// FetchDiariosWorker discovers the diarios of any registered DiarioSource, the first
+// stage of the ingestion pipeline
+type FetchDiariosWorker struct {
 	// Embed worker defaults
-	river.WorkerDefaults[GovernoPiauiArgs]
+	river.WorkerDefaults[FetchDiariosArgs]
 
 	// Add dependencies
-	DiarioService *diarios.DiarioService
-	Uploader      *storage.SpacesUploader
+	Ingester           *diarios.Ingester
+	InstitutionService *institutions.InstitutionService
+	BackfillService    *diarios.BackfillService
 }
 
-// NewDiarioWorker creates a new DiarioWorker
-func NewDiarioWorker(diarioService *diarios.DiarioService, uploader *storage.SpacesUploader) *DiarioWorker {
-	return &DiarioWorker{
-		DiarioService: diarioService,
-		Uploader:      uploader,
+// NewFetchDiariosWorker creates a new FetchDiariosWorker
+func NewFetchDiariosWorker(ingester *diarios.Ingester, institutionService *institutions.InstitutionService, backfillService *diarios.BackfillService) *FetchDiariosWorker {
+	return &FetchDiariosWorker{
+		Ingester:           ingester,
+		InstitutionService: institutionService,
+		BackfillService:    backfillService,
 	}
 }
 
-// NewGovernoPiauiWorker creates a new GovernoPiauiWorker
-func NewGovernoPiauiWorker(diarioService *diarios.DiarioService, uploader *storage.SpacesUploader) *GovernoPiauiWorker {
-	return &GovernoPiauiWorker{
-		DiarioService: diarioService,
-		Uploader:      uploader,
+// Work discovers the editions of the source registered under the job's slug and
+// enqueues their download
+func (w *FetchDiariosWorker) Work(ctx context.Context, job *river.Job[FetchDiariosArgs]) error {
+	src, ok := diarios.GetSource(job.Args.Slug)
+	if !ok {
+		return river.JobCancel(fmt.Errorf("no diario source registered for %s", job.Args.Slug))
 	}
-}
 
-// Work processes the fetch job for Diario dos Municipios
-func (w *DiarioWorker) Work(ctx context.Context, job *river.Job[DiarioDosMunicipiosArgs]) error {
-	log.Printf("🔄 Starting job to fetch Diário dos Municípios (ID: %d)", job.ID)
+	meta := src.Metadata()
+	log.Printf("🔄 Starting job to fetch Diários from %s (ID: %d)", meta.Name, job.ID)
 
-	// Fetch and process the diario
-	entries, err := diarios.FetchDiarioDosMunicipiosPiaui(ctx, w.Uploader, w.DiarioService)
+	institution, err := w.InstitutionService.GetBySlug(ctx, job.Args.Slug)
 	if err != nil {
-		return fmt.Errorf("failed to fetch diario dos municipios: %w", err)
+		return err
 	}
 
-	log.Printf("✅ Job completed successfully. Processed %d diário(s) from Municípios do Piauí", len(entries))
-	return nil
-}
-
-// Work processes the fetch job for Governo do Piauí diarios
-func (w *GovernoPiauiWorker) Work(ctx context.Context, job *river.Job[GovernoPiauiArgs]) error {
-	log.Printf("🔄 Starting job to fetch Diários from Governo do Piauí (ID: %d)", job.ID)
+	if !institution.Active {
+		log.Printf("⏭️ Skipping inactive institution %s", institution.Slug)
+		return nil
+	}
 
 	// Parse date if provided, otherwise use current date
 	var fetchDate time.Time
-	var err error
 
 	if job.Args.Date != "" {
 		fetchDate, err = time.Parse("2006-01-02", job.Args.Date)
@@ -93,94 +76,76 @@ func (w *GovernoPiauiWorker) Work(ctx context.Context, job *river.Job[GovernoPia
 		fetchDate = time.Now()
 	}
 
-	// Fetch and process the diarios
-	entries, err := diarios.FetchGovernoPiauiDiarios(ctx, fetchDate, w.Uploader, w.DiarioService)
+	// Discover the diarios and enqueue the download of the new ones
+	editions, err := src.Discover(ctx, fetchDate, fetchDate)
 	if err != nil {
-		return fmt.Errorf("failed to fetch diarios from Governo do Piauí: %w", err)
+		return fmt.Errorf("failed to discover editions of %s: %w", meta.Name, err)
 	}
 
-	log.Printf("✅ Job completed successfully. Processed %d diário(s) from Governo do Piauí", len(entries))
-	return nil
-}
+	registered, err := registerEditions(ctx, w.Ingester, job.JobRow, institution, editions)
+	if err != nil {
+		return err
+	}
 
-// MaxRetries defines max attempts for this job
-func (w *DiarioWorker) MaxRetries(job *river.Job[DiarioDosMunicipiosArgs]) int {
-	return 3 // Retry up to 3 times
-}
+	// A backfilled day counts once its editions are registered, not when enqueued
+	if job.Args.BackfillID != 0 {
+		if err := w.BackfillService.MarkDayFetched(ctx, job.Args.BackfillID, fetchDate); err != nil {
+			return fmt.Errorf("failed to record backfill progress: %w", err)
+		}
+	}
 
-// Timeout sets the maximum execution time for this job
-func (w *DiarioWorker) Timeout(job *river.Job[DiarioDosMunicipiosArgs]) time.Duration {
-	return 1 * time.Hour // Allow up to 1 hour to fetch and process
+	log.Printf("✅ Job completed successfully. Enqueued %d of %d diário(s) from %s", registered, len(editions), meta.Name)
+	return nil
 }
 
 // MaxRetries defines max attempts for this job
-func (w *GovernoPiauiWorker) MaxRetries(job *river.Job[GovernoPiauiArgs]) int {
+func (w *FetchDiariosWorker) MaxRetries(job *river.Job[FetchDiariosArgs]) int {
 	return 3 // Retry up to 3 times
 }
 
-// Timeout sets the maximum execution time for this job
-func (w *GovernoPiauiWorker) Timeout(job *river.Job[GovernoPiauiArgs]) time.Duration {
-	return 10 * time.Minute // Allow up to 10 minutes to fetch and process
+// Timeout sets the maximum execution time for this job, as declared by the source
+func (w *FetchDiariosWorker) Timeout(job *river.Job[FetchDiariosArgs]) time.Duration {
+	if src, ok := diarios.GetSource(job.Args.Slug); ok {
+		return src.Metadata().Timeout
+	}
+	return 10 * time.Minute
 }
 
-// CreateDiarioDosMunicipiosPeriodicJob returns a periodic job for Diario dos Municipios
-func CreateDiarioDosMunicipiosPeriodicJob() *river.PeriodicJob {
-	return river.NewPeriodicJob(
-		// Run every hour
-		river.PeriodicInterval(1*time.Hour),
-
-		// Args constructor function
-		func() (river.JobArgs, *river.InsertOpts) {
-			return DiarioDosMunicipiosArgs{}, &river.InsertOpts{
-				Queue:    "default",
-				Priority: 1, // Higher number = higher priority
-			}
-		},
-
-		// Options - use nil for default options
-		nil,
-	)
-}
+// CreateFetchDiariosPeriodicJobs returns one periodic job per registered source
+func CreateFetchDiariosPeriodicJobs() []*river.PeriodicJob {
+	var periodicJobs []*river.PeriodicJob
 
-// CreateGovernoPiauiPeriodicJob returns a periodic job for Governo do Piauí
-func CreateGovernoPiauiPeriodicJob() *river.PeriodicJob {
-	return river.NewPeriodicJob(
-		// Run every hour
-		river.PeriodicInterval(1*time.Hour),
-
-		// Args constructor function
-		func() (river.JobArgs, *river.InsertOpts) {
-			return GovernoPiauiArgs{}, &river.InsertOpts{
-				Queue:    "default",
-				Priority: 1, // Higher number = higher priority
-			}
-		},
-
-		// Options - use nil for default options
-		nil,
-	)
-}
+	for _, src := range diarios.Sources() {
+		meta := src.Metadata()
 
-// ScheduleDiarioDosMunicipiosJob schedules a job to fetch the Diario dos Municipios to run immediately
-func ScheduleDiarioDosMunicipiosJob(ctx context.Context, client *river.Client[pgx.Tx]) error {
-	// Insert a job to run immediately
-	_, err := client.Insert(ctx, DiarioDosMunicipiosArgs{}, &river.InsertOpts{
-		Queue:    "default",
-		Priority: 1, // Higher number = higher priority
-	})
+		periodicJobs = append(periodicJobs, river.NewPeriodicJob(
+			river.PeriodicInterval(meta.PollInterval),
 
-	if err != nil {
-		return fmt.Errorf("failed to schedule immediate diario job: %w", err)
+			// Args constructor function
+			func() (river.JobArgs, *river.InsertOpts) {
+				return FetchDiariosArgs{Slug: meta.Slug}, &river.InsertOpts{
+					Queue:    "default",
+					Priority: 1, // Higher number = higher priority
+				}
+			},
+
+			// Options - use nil for default options
+			nil,
+		))
 	}
 
-	log.Printf("✅ Scheduled immediate job to fetch Diario dos Municipios")
-	return nil
+	return periodicJobs
 }
 
-// ScheduleGovernoPiauiJob schedules a job to fetch the Governo do Piauí diarios to run immediately
-func ScheduleGovernoPiauiJob(ctx context.Context, client *river.Client[pgx.Tx], date string) error {
+// ScheduleFetchDiariosJob schedules a job to fetch the diarios of a source to run immediately
+func ScheduleFetchDiariosJob(ctx context.Context, client *river.Client[pgx.Tx], slug, date string) error {
+	if _, ok := diarios.GetSource(slug); !ok {
+		return fmt.Errorf("no diario source registered for %s", slug)
+	}
+
 	// Insert a job to run immediately
-	_, err := client.Insert(ctx, GovernoPiauiArgs{
+	_, err := client.Insert(ctx, FetchDiariosArgs{
+		Slug: slug,
 		Date: date,
 	}, &river.InsertOpts{
 		Queue:    "default",
@@ -188,9 +153,9 @@ func ScheduleGovernoPiauiJob(ctx context.Context, client *river.Client[pgx.Tx],
 	})
 
 	if err != nil {
-		return fmt.Errorf("failed to schedule immediate governo job: %w", err)
+		return fmt.Errorf("failed to schedule immediate %s job: %w", slug, err)
 	}
 
-	log.Printf("✅ Scheduled immediate job to fetch Governo do Piauí diarios")
+	log.Printf("✅ Scheduled immediate job to fetch %s diarios", slug)
 	return nil
 }
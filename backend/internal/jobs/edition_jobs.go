@@ -0,0 +1,273 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/riverqueue/river"
+	"radaroficial.app/internal/diarios"
+	"radaroficial.app/internal/institutions"
+)
+
+const (
+	// maxGapsPerRun bounds how many missing editions a single gap fill run enqueues
+	maxGapsPerRun = 100
+
+	// gapLookback is how many editions before the latest one a gap fill checks when not
+	// given the first edition, about a year of editions
+	gapLookback = 365
+)
+
+// FetchEditionArgs contains arguments for the job
+type FetchEditionArgs struct {
+	Slug   string `json:"slug"`   // Slug of the institution whose source should be fetched
+	Number int    `json:"number"` // Edition number
+}
+
+// Kind returns the kind of job
+func (FetchEditionArgs) Kind() string { return "fetch_diario_edition" }
+
+// FetchEditionWorker fetches a single edition by number from a NumberedSource
+type FetchEditionWorker struct {
+	// Embed worker defaults
+	river.WorkerDefaults[FetchEditionArgs]
+
+	// Add dependencies
+	Ingester           *diarios.Ingester
+	InstitutionService *institutions.InstitutionService
+}
+
+// NewFetchEditionWorker creates a new FetchEditionWorker
+func NewFetchEditionWorker(ingester *diarios.Ingester, institutionService *institutions.InstitutionService) *FetchEditionWorker {
+	return &FetchEditionWorker{
+		Ingester:           ingester,
+		InstitutionService: institutionService,
+	}
+}
+
+// Work fetches the requested edition and enqueues its download
+func (w *FetchEditionWorker) Work(ctx context.Context, job *river.Job[FetchEditionArgs]) error {
+	src, err := numberedSource(job.Args.Slug)
+	if err != nil {
+		return river.JobCancel(err)
+	}
+
+	log.Printf("🔄 Starting job to fetch edição %d from %s (ID: %d)", job.Args.Number, src.Metadata().Name, job.ID)
+
+	institution, err := w.InstitutionService.GetBySlug(ctx, job.Args.Slug)
+	if err != nil {
+		return err
+	}
+
+	edition, err := src.FetchEdition(ctx, job.Args.Number)
+	if err != nil {
+		return fmt.Errorf("failed to fetch edição %d: %w", job.Args.Number, err)
+	}
+
+	registered, err := registerEditions(ctx, w.Ingester, job.JobRow, institution, []diarios.Edition{edition})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✅ Job completed successfully. Enqueued %d diário(s) for edição %d", registered, job.Args.Number)
+	return nil
+}
+
+// MaxRetries defines max attempts for this job
+func (w *FetchEditionWorker) MaxRetries(job *river.Job[FetchEditionArgs]) int {
+	return 3 // Retry up to 3 times
+}
+
+// Timeout sets the maximum execution time for this job, as declared by the source
+func (w *FetchEditionWorker) Timeout(job *river.Job[FetchEditionArgs]) time.Duration {
+	if src, ok := diarios.GetSource(job.Args.Slug); ok {
+		return src.Metadata().Timeout
+	}
+	return 10 * time.Minute
+}
+
+// FillEditionGapsArgs contains arguments for the job
+type FillEditionGapsArgs struct {
+	Slug         string `json:"slug"`                    // Slug of the institution whose gaps should be filled
+	FirstEdition int    `json:"first_edition,omitempty"` // First edition checked, gapLookback editions before the latest if 0
+}
+
+// Kind returns the kind of job
+func (FillEditionGapsArgs) Kind() string { return "fill_edition_gaps" }
+
+// FillEditionGapsWorker detects missing edition numbers and enqueues a fetch for each
+type FillEditionGapsWorker struct {
+	// Embed worker defaults
+	river.WorkerDefaults[FillEditionGapsArgs]
+
+	// Add dependencies
+	DiarioService      *diarios.DiarioService
+	InstitutionService *institutions.InstitutionService
+}
+
+// NewFillEditionGapsWorker creates a new FillEditionGapsWorker
+func NewFillEditionGapsWorker(diarioService *diarios.DiarioService, institutionService *institutions.InstitutionService) *FillEditionGapsWorker {
+	return &FillEditionGapsWorker{
+		DiarioService:      diarioService,
+		InstitutionService: institutionService,
+	}
+}
+
+// Work compares the editions stored with the latest one published and enqueues the missing ones
+func (w *FillEditionGapsWorker) Work(ctx context.Context, job *river.Job[FillEditionGapsArgs]) error {
+	src, err := numberedSource(job.Args.Slug)
+	if err != nil {
+		return river.JobCancel(err)
+	}
+
+	institution, err := w.InstitutionService.GetBySlug(ctx, job.Args.Slug)
+	if err != nil {
+		return err
+	}
+
+	if !institution.Active {
+		log.Printf("⏭️ Skipping inactive institution %s", institution.Slug)
+		return nil
+	}
+
+	latest, err := src.LatestEdition(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch latest edition: %w", err)
+	}
+
+	first := job.Args.FirstEdition
+	if first <= 0 {
+		first = max(latest.Number-gapLookback+1, 1)
+	}
+
+	gaps, err := w.DiarioService.FindEditionGaps(ctx, institution.ID, first, latest.Number)
+	if err != nil {
+		return fmt.Errorf("failed to find edition gaps: %w", err)
+	}
+
+	if len(gaps) == 0 {
+		log.Printf("✅ No edition gaps for %s from edição %d to %d", job.Args.Slug, first, latest.Number)
+		return nil
+	}
+
+	if len(gaps) > maxGapsPerRun {
+		log.Printf("⚠️ Found %d missing editions for %s, enqueuing the first %d", len(gaps), job.Args.Slug, maxGapsPerRun)
+		gaps = gaps[:maxGapsPerRun]
+	}
+
+	client := river.ClientFromContext[pgx.Tx](ctx)
+
+	params := make([]river.InsertManyParams, 0, len(gaps))
+	for _, number := range gaps {
+		params = append(params, river.InsertManyParams{
+			Args: FetchEditionArgs{Slug: job.Args.Slug, Number: number},
+			InsertOpts: &river.InsertOpts{
+				Queue:      "backfill",
+				Priority:   4,
+				UniqueOpts: pendingUniqueOpts,
+			},
+		})
+	}
+
+	if _, err := client.InsertMany(ctx, params); err != nil {
+		return fmt.Errorf("failed to enqueue missing editions: %w", err)
+	}
+
+	log.Printf("✅ Enqueued %d missing edition(s) of %s: %v", len(gaps), job.Args.Slug, gaps)
+	return nil
+}
+
+// Timeout sets the maximum execution time for this job, as declared by the source, whose
+// latest edition may be read with a headless browser
+func (w *FillEditionGapsWorker) Timeout(job *river.Job[FillEditionGapsArgs]) time.Duration {
+	if src, ok := diarios.GetSource(job.Args.Slug); ok {
+		return src.Metadata().Timeout
+	}
+	return 10 * time.Minute
+}
+
+// CreateFillEditionGapsPeriodicJobs returns a daily gap fill job per numbered source
+func CreateFillEditionGapsPeriodicJobs() []*river.PeriodicJob {
+	var periodicJobs []*river.PeriodicJob
+
+	for _, src := range diarios.Sources() {
+		if _, ok := src.(diarios.NumberedSource); !ok {
+			continue
+		}
+
+		slug := src.Metadata().Slug
+
+		periodicJobs = append(periodicJobs, river.NewPeriodicJob(
+			// Run once a day
+			river.PeriodicInterval(24*time.Hour),
+
+			// Args constructor function
+			func() (river.JobArgs, *river.InsertOpts) {
+				return FillEditionGapsArgs{Slug: slug}, &river.InsertOpts{
+					Queue:    "default",
+					Priority: 2,
+				}
+			},
+
+			// Options - use nil for default options
+			nil,
+		))
+	}
+
+	return periodicJobs
+}
+
+// ScheduleFetchEditionJob schedules a job to fetch an edition by number to run immediately
+func ScheduleFetchEditionJob(ctx context.Context, client *river.Client[pgx.Tx], slug string, number int) error {
+	if _, err := numberedSource(slug); err != nil {
+		return err
+	}
+
+	_, err := client.Insert(ctx, FetchEditionArgs{Slug: slug, Number: number}, &river.InsertOpts{
+		Queue:    "default",
+		Priority: 1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule edição %d of %s: %w", number, slug, err)
+	}
+
+	log.Printf("✅ Scheduled immediate job to fetch edição %d of %s", number, slug)
+	return nil
+}
+
+// ScheduleFillEditionGapsJob schedules a gap fill job to run immediately, from
+// firstEdition or the default lookback if 0
+func ScheduleFillEditionGapsJob(ctx context.Context, client *river.Client[pgx.Tx], slug string, firstEdition int) error {
+	if _, err := numberedSource(slug); err != nil {
+		return err
+	}
+
+	_, err := client.Insert(ctx, FillEditionGapsArgs{Slug: slug, FirstEdition: firstEdition}, &river.InsertOpts{
+		Queue:    "default",
+		Priority: 1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule gap fill of %s: %w", slug, err)
+	}
+
+	log.Printf("✅ Scheduled immediate job to fill edition gaps of %s", slug)
+	return nil
+}
+
+// numberedSource returns the registered source for slug if it supports fetching by number
+func numberedSource(slug string) (diarios.NumberedSource, error) {
+	src, ok := diarios.GetSource(slug)
+	if !ok {
+		return nil, fmt.Errorf("no diario source registered for %s", slug)
+	}
+
+	numbered, ok := src.(diarios.NumberedSource)
+	if !ok {
+		return nil, fmt.Errorf("source %s cannot fetch editions by number", slug)
+	}
+
+	return numbered, nil
+}
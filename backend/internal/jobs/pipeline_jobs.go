@@ -0,0 +1,456 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/rivertype"
+	"radaroficial.app/internal/diarios"
+	"radaroficial.app/internal/institutions"
+	"radaroficial.app/internal/model"
+)
+
+// The ingestion of a diario is split in stages, each one a job enqueued by the previous
+// stage in the same transaction that records the diario's new status:
+//
+//	fetch_diarios (discover) → download_diario → extract_diario → index_diario → finalize_diario
+//
+// The stage jobs are unique by args and carry the diario version, so a republished
+// diario goes through extraction and indexing again.
+
+// DownloadDiarioArgs contains arguments for the job
+type DownloadDiarioArgs struct {
+	DiarioID       int        `json:"diario_id"`
+	Slug           string     `json:"slug"`                       // Slug of the institution, selects the source
+	DownloadURL    string     `json:"download_url"`               // URL of the PDF at the source
+	LastModifiedAt *time.Time `json:"last_modified_at,omitempty"` // Modification time listed by the source
+}
+
+// Kind returns the kind of job
+func (DownloadDiarioArgs) Kind() string { return "download_diario" }
+
+// InsertOpts sets the max attempts of the job
+func (DownloadDiarioArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{MaxAttempts: 5}
+}
+
+// ExtractDiarioArgs contains arguments for the job
+type ExtractDiarioArgs struct {
+	DiarioID int    `json:"diario_id"`
+	Slug     string `json:"slug"`
+	Version  int    `json:"version"`
+}
+
+// Kind returns the kind of job
+func (ExtractDiarioArgs) Kind() string { return "extract_diario" }
+
+// InsertOpts sets the max attempts of the job
+func (ExtractDiarioArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{MaxAttempts: 5}
+}
+
+// IndexDiarioArgs contains arguments for the job
+type IndexDiarioArgs struct {
+	DiarioID int    `json:"diario_id"`
+	Slug     string `json:"slug"`
+	Version  int    `json:"version"`
+}
+
+// Kind returns the kind of job
+func (IndexDiarioArgs) Kind() string { return "index_diario" }
+
+// InsertOpts sets the max attempts of the job, weaviate outages are retried with backoff
+func (IndexDiarioArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{MaxAttempts: 10}
+}
+
+// FinalizeDiarioArgs contains arguments for the job
+type FinalizeDiarioArgs struct {
+	DiarioID int    `json:"diario_id"`
+	Slug     string `json:"slug"`
+	Version  int    `json:"version"`
+}
+
+// Kind returns the kind of job
+func (FinalizeDiarioArgs) Kind() string { return "finalize_diario" }
+
+// InsertOpts sets the max attempts of the job
+func (FinalizeDiarioArgs) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{MaxAttempts: 5}
+}
+
+// DownloadDiarioWorker downloads the PDF of a registered diario and stores it
+type DownloadDiarioWorker struct {
+	// Embed worker defaults
+	river.WorkerDefaults[DownloadDiarioArgs]
+
+	// Add dependencies
+	Ingester *diarios.Ingester
+}
+
+// NewDownloadDiarioWorker creates a new DownloadDiarioWorker
+func NewDownloadDiarioWorker(ingester *diarios.Ingester) *DownloadDiarioWorker {
+	return &DownloadDiarioWorker{Ingester: ingester}
+}
+
+// Work downloads the PDF and enqueues its extraction
+func (w *DownloadDiarioWorker) Work(ctx context.Context, job *river.Job[DownloadDiarioArgs]) error {
+	src, ok := diarios.GetSource(job.Args.Slug)
+	if !ok {
+		return river.JobCancel(fmt.Errorf("no diario source registered for %s", job.Args.Slug))
+	}
+
+	diario, err := loadDiario(ctx, w.Ingester.Service, job.Args.DiarioID)
+	if err != nil {
+		return err
+	}
+
+	changed, err := w.Ingester.Download(ctx, src, diario, job.Args.DownloadURL, job.Args.LastModifiedAt)
+	if errors.Is(err, diarios.ErrDuplicate) {
+		// A republished version may replace atos already indexed, which now belong to the
+		// other diario
+		if diario.IndexedAt != nil {
+			if _, err := w.Ingester.VectorStore.DeleteDiario(ctx, diario.ID); err != nil {
+				return fmt.Errorf("failed to remove atos of superseded diário %d: %w", diario.ID, err)
+			}
+		}
+		return advanceDiario(ctx, w.Ingester.Service, job.JobRow, diario.ID, diarios.StatusSuperseded, nil)
+	}
+	if err != nil {
+		return stageFailed(ctx, w.Ingester.Service, job.JobRow, diario.ID, err)
+	}
+
+	if !changed {
+		return nil
+	}
+
+	// The PDF is recorded with the status and the extraction of its version, so a job that
+	// dies in between downloads and records it again on retry
+	tx, err := w.Ingester.Service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	previousVersion := diario.Version
+	if err := w.Ingester.Service.RecordPDF(ctx, tx, diario); err != nil {
+		return stageFailed(ctx, w.Ingester.Service, job.JobRow, diario.ID, err)
+	}
+
+	err = advanceDiarioTx(ctx, tx, w.Ingester.Service, job.JobRow, diario.ID, diarios.StatusDownloaded, ExtractDiarioArgs{
+		DiarioID: diario.ID,
+		Slug:     job.Args.Slug,
+		Version:  diario.Version,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	if diario.Version != previousVersion {
+		log.Printf("🔁 Recorded version %d of diário %d", diario.Version, diario.ID)
+	}
+	return nil
+}
+
+// Timeout sets the maximum execution time for this job, as declared by the source
+func (w *DownloadDiarioWorker) Timeout(job *river.Job[DownloadDiarioArgs]) time.Duration {
+	if src, ok := diarios.GetSource(job.Args.Slug); ok {
+		return src.Metadata().Timeout
+	}
+	return 10 * time.Minute
+}
+
+// ExtractDiarioWorker converts a stored PDF and segments it into atos
+type ExtractDiarioWorker struct {
+	// Embed worker defaults
+	river.WorkerDefaults[ExtractDiarioArgs]
+
+	// Add dependencies
+	Ingester *diarios.Ingester
+}
+
+// NewExtractDiarioWorker creates a new ExtractDiarioWorker
+func NewExtractDiarioWorker(ingester *diarios.Ingester) *ExtractDiarioWorker {
+	return &ExtractDiarioWorker{Ingester: ingester}
+}
+
+// Work extracts the atos of the diario and enqueues their indexing
+func (w *ExtractDiarioWorker) Work(ctx context.Context, job *river.Job[ExtractDiarioArgs]) error {
+	diario, err := loadDiarioVersion(ctx, w.Ingester.Service, job.Args.DiarioID, job.Args.Version)
+	if err != nil || diario == nil {
+		return err
+	}
+
+	if _, err := w.Ingester.Extract(ctx, diario); err != nil {
+		return stageFailed(ctx, w.Ingester.Service, job.JobRow, diario.ID, err)
+	}
+
+	return advanceDiario(ctx, w.Ingester.Service, job.JobRow, diario.ID, diarios.StatusExtracted, IndexDiarioArgs(job.Args))
+}
+
+// Timeout leaves room for OCR of scanned editions
+func (w *ExtractDiarioWorker) Timeout(job *river.Job[ExtractDiarioArgs]) time.Duration {
+	return 30 * time.Minute
+}
+
+// IndexDiarioWorker indexes the atos of a diario in weaviate
+type IndexDiarioWorker struct {
+	// Embed worker defaults
+	river.WorkerDefaults[IndexDiarioArgs]
+
+	// Add dependencies
+	Ingester           *diarios.Ingester
+	InstitutionService *institutions.InstitutionService
+}
+
+// NewIndexDiarioWorker creates a new IndexDiarioWorker
+func NewIndexDiarioWorker(ingester *diarios.Ingester, institutionService *institutions.InstitutionService) *IndexDiarioWorker {
+	return &IndexDiarioWorker{
+		Ingester:           ingester,
+		InstitutionService: institutionService,
+	}
+}
+
+// Work indexes the atos and enqueues the finalization of the diario
+func (w *IndexDiarioWorker) Work(ctx context.Context, job *river.Job[IndexDiarioArgs]) error {
+	diario, err := loadDiarioVersion(ctx, w.Ingester.Service, job.Args.DiarioID, job.Args.Version)
+	if err != nil || diario == nil {
+		return err
+	}
+
+	institution, err := w.InstitutionService.GetBySlug(ctx, job.Args.Slug)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Ingester.Index(ctx, diario, institution); err != nil {
+		return stageFailed(ctx, w.Ingester.Service, job.JobRow, diario.ID, err)
+	}
+
+	// The diario becomes indexed once finalized
+	return advanceDiario(ctx, w.Ingester.Service, job.JobRow, diario.ID, "", FinalizeDiarioArgs(job.Args))
+}
+
+// Timeout sets the maximum execution time for this job
+func (w *IndexDiarioWorker) Timeout(job *river.Job[IndexDiarioArgs]) time.Duration {
+	return 10 * time.Minute
+}
+
+// FinalizeDiarioWorker marks a fully ingested diario as indexed
+type FinalizeDiarioWorker struct {
+	// Embed worker defaults
+	river.WorkerDefaults[FinalizeDiarioArgs]
+
+	// Add dependencies
+	Ingester *diarios.Ingester
+}
+
+// NewFinalizeDiarioWorker creates a new FinalizeDiarioWorker
+func NewFinalizeDiarioWorker(ingester *diarios.Ingester) *FinalizeDiarioWorker {
+	return &FinalizeDiarioWorker{Ingester: ingester}
+}
+
+// Work marks the diario as indexed
+func (w *FinalizeDiarioWorker) Work(ctx context.Context, job *river.Job[FinalizeDiarioArgs]) error {
+	diario, err := loadDiarioVersion(ctx, w.Ingester.Service, job.Args.DiarioID, job.Args.Version)
+	if err != nil || diario == nil {
+		return err
+	}
+
+	if err := advanceDiario(ctx, w.Ingester.Service, job.JobRow, diario.ID, diarios.StatusIndexed, nil); err != nil {
+		return err
+	}
+
+	log.Printf("✅ Diário %d (version %d) is indexed", diario.ID, diario.Version)
+	return nil
+}
+
+// registerEditions registers discovered editions and enqueues the download of the new
+// and republished ones in the queue of the discovering job, each edition in its own
+// transaction. Failures are reported after trying every edition so the job is retried.
+func registerEditions(ctx context.Context, ingester *diarios.Ingester, parent *rivertype.JobRow, institution *model.Institution, editions []diarios.Edition) (int, error) {
+	client := river.ClientFromContext[pgx.Tx](ctx)
+
+	var registered int
+	var errs []error
+
+	for _, edition := range editions {
+		err := func() error {
+			tx, err := ingester.Service.DB.Begin(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to begin transaction: %w", err)
+			}
+			defer tx.Rollback(ctx)
+
+			diario, err := ingester.Register(ctx, tx, institution, edition)
+			if err != nil || diario == nil {
+				return err
+			}
+
+			_, err = client.InsertTx(ctx, tx, DownloadDiarioArgs{
+				DiarioID:       diario.ID,
+				Slug:           institution.Slug,
+				DownloadURL:    edition.DownloadURL,
+				LastModifiedAt: edition.LastModifiedAt,
+			}, stageInsertOpts(parent))
+			if err != nil {
+				return fmt.Errorf("failed to enqueue download: %w", err)
+			}
+
+			if err := tx.Commit(ctx); err != nil {
+				return err
+			}
+
+			registered++
+			return nil
+		}()
+		if err != nil {
+			log.Printf("❌ Failed to register %s: %v", edition.Description, err)
+			errs = append(errs, err)
+		}
+	}
+
+	return registered, errors.Join(errs...)
+}
+
+// advanceDiario moves a diario to status, unless empty, and enqueues the job of the
+// next stage, if any, in one transaction
+func advanceDiario(ctx context.Context, service *diarios.DiarioService, parent *rivertype.JobRow, diarioID int, status string, next river.JobArgs) error {
+	tx, err := service.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := advanceDiarioTx(ctx, tx, service, parent, diarioID, status, next); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// advanceDiarioTx is advanceDiario in a transaction of the caller
+func advanceDiarioTx(ctx context.Context, tx pgx.Tx, service *diarios.DiarioService, parent *rivertype.JobRow, diarioID int, status string, next river.JobArgs) error {
+	if status != "" {
+		err := service.Transition(ctx, tx, diarioID, status)
+		if errors.Is(err, diarios.ErrInvalidTransition) {
+			// Another job moved the diario on, this stage is stale
+			return river.JobCancel(err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to set status of diário %d: %w", diarioID, err)
+		}
+	}
+
+	if next != nil {
+		client := river.ClientFromContext[pgx.Tx](ctx)
+		if _, err := client.InsertTx(ctx, tx, next, stageInsertOpts(parent)); err != nil {
+			return fmt.Errorf("failed to enqueue %s of diário %d: %w", next.Kind(), diarioID, err)
+		}
+	}
+
+	return nil
+}
+
+// stageFailed records the error of a stage attempt on the diario, moving it to failed
+// once the job has exhausted the max attempts set by the InsertOpts of its args, and
+// returns the error so River retries it
+func stageFailed(ctx context.Context, service *diarios.DiarioService, job *rivertype.JobRow, diarioID int, err error) error {
+	if job.Attempt >= job.MaxAttempts {
+		if markErr := service.MarkFailed(ctx, diarioID, err); markErr != nil {
+			log.Printf("⚠️ Failed to mark diário %d as failed: %v", diarioID, markErr)
+		}
+	} else if recordErr := service.RecordError(ctx, diarioID, err); recordErr != nil {
+		log.Printf("⚠️ Failed to record error of diário %d: %v", diarioID, recordErr)
+	}
+
+	return err
+}
+
+// stageInsertOpts keeps the stages of a diario in the queue and priority of the job that
+// started its ingestion, so backfills stay in the backfill queue
+func stageInsertOpts(parent *rivertype.JobRow) *river.InsertOpts {
+	return &river.InsertOpts{
+		Queue:      parent.Queue,
+		Priority:   parent.Priority,
+		UniqueOpts: river.UniqueOpts{ByArgs: true},
+	}
+}
+
+// loadDiario returns a diario, cancelling the job when it was deleted
+func loadDiario(ctx context.Context, service *diarios.DiarioService, id int) (*model.Diario, error) {
+	diario, err := service.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load diário %d: %w", id, err)
+	}
+
+	if diario == nil {
+		return nil, river.JobCancel(fmt.Errorf("diário %d not found", id))
+	}
+
+	return diario, nil
+}
+
+// loadDiarioVersion returns a diario, or nil when a newer version superseded the one
+// the job was enqueued for
+func loadDiarioVersion(ctx context.Context, service *diarios.DiarioService, id, version int) (*model.Diario, error) {
+	diario, err := loadDiario(ctx, service, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if diario.Version != version {
+		log.Printf("⏭️ Skipping version %d of diário %d, superseded by version %d", version, id, diario.Version)
+		return nil, nil
+	}
+
+	return diario, nil
+}
+
+// ScheduleIndexDiarioJob schedules the reindexing of a single diario to run immediately.
+// Its weaviate objects are replaced in place, so it can be scheduled repeatedly.
+func ScheduleIndexDiarioJob(ctx context.Context, client *river.Client[pgx.Tx], service *diarios.DiarioService, slug string, diarioID int) error {
+	diario, err := service.GetByID(ctx, diarioID)
+	if err != nil {
+		return fmt.Errorf("failed to load diário %d: %w", diarioID, err)
+	}
+
+	if diario == nil {
+		return fmt.Errorf("diário %d not found", diarioID)
+	}
+
+	institution, err := institutions.NewInstitutionService(service.DB).GetBySlug(ctx, slug)
+	if err != nil {
+		return err
+	}
+
+	if institution.ID != diario.InstitutionID {
+		return fmt.Errorf("diário %d is not from %s", diarioID, slug)
+	}
+
+	// Only diarios whose atos were extracted have something to index
+	if diario.Status != diarios.StatusExtracted && diario.Status != diarios.StatusIndexed {
+		return fmt.Errorf("diário %d is %s and cannot be indexed", diarioID, diario.Status)
+	}
+
+	// Not unique by args, the job that first indexed this version has already completed
+	args := IndexDiarioArgs{DiarioID: diario.ID, Slug: slug, Version: diario.Version}
+	if _, err := client.Insert(ctx, args, &river.InsertOpts{
+		Queue:    "default",
+		Priority: 1,
+	}); err != nil {
+		return fmt.Errorf("failed to schedule indexing of diário %d: %w", diarioID, err)
+	}
+
+	log.Printf("✅ Scheduled immediate job to reindex diário %d of %s", diarioID, slug)
+	return nil
+}
@@ -0,0 +1,183 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/riverqueue/river"
+	"radaroficial.app/internal/diarios"
+	"radaroficial.app/internal/institutions"
+	"radaroficial.app/internal/model"
+)
+
+const (
+	// redriveAfter is how long a diario stays in an intermediate status before the job
+	// of its next stage is considered lost
+	redriveAfter = 2 * time.Hour
+
+	// redriveInterval is how often stuck diarios are re-driven. Re-driven stages are unique
+	// within it, so a stage is enqueued at most once per run.
+	redriveInterval = time.Hour
+
+	// maxRedrivesPerRun bounds how many diarios of a status a single run re-drives
+	maxRedrivesPerRun = 100
+)
+
+// redriveStatuses lists the statuses re-driven periodically. Failed diarios are only
+// re-driven on request, as their error usually needs a fix first.
+var redriveStatuses = []string{diarios.StatusDiscovered, diarios.StatusDownloaded, diarios.StatusExtracted}
+
+// RedriveDiariosArgs contains arguments for the job
+type RedriveDiariosArgs struct {
+	Status string `json:"status,omitempty"` // Status to re-drive, every intermediate status if empty
+}
+
+// Kind returns the kind of job
+func (RedriveDiariosArgs) Kind() string { return "redrive_diarios" }
+
+// RedriveDiariosWorker re-enqueues the next stage of diarios whose job was lost, e.g.
+// discarded after a deploy, or that failed
+type RedriveDiariosWorker struct {
+	// Embed worker defaults
+	river.WorkerDefaults[RedriveDiariosArgs]
+
+	// Add dependencies
+	DiarioService      *diarios.DiarioService
+	InstitutionService *institutions.InstitutionService
+}
+
+// NewRedriveDiariosWorker creates a new RedriveDiariosWorker
+func NewRedriveDiariosWorker(diarioService *diarios.DiarioService, institutionService *institutions.InstitutionService) *RedriveDiariosWorker {
+	return &RedriveDiariosWorker{
+		DiarioService:      diarioService,
+		InstitutionService: institutionService,
+	}
+}
+
+// Work enqueues the next stage of every diario stuck in the requested statuses
+func (w *RedriveDiariosWorker) Work(ctx context.Context, job *river.Job[RedriveDiariosArgs]) error {
+	statuses := redriveStatuses
+	olderThan := redriveAfter
+	if job.Args.Status != "" {
+		statuses = []string{job.Args.Status}
+		olderThan = 0
+	}
+
+	client := river.ClientFromContext[pgx.Tx](ctx)
+
+	var params []river.InsertManyParams
+	for _, status := range statuses {
+		stuck, err := w.DiarioService.ListStuck(ctx, status, olderThan, maxRedrivesPerRun)
+		if err != nil {
+			return fmt.Errorf("failed to list %s diarios: %w", status, err)
+		}
+
+		for _, diario := range stuck {
+			institution, err := w.InstitutionService.GetByID(ctx, diario.InstitutionID)
+			if err != nil {
+				return fmt.Errorf("failed to load institution of diário %d: %w", diario.ID, err)
+			}
+			if institution == nil {
+				continue
+			}
+
+			args, err := nextStageArgs(diario, institution.Slug)
+			if err != nil {
+				log.Printf("⚠️ Cannot re-drive diário %d: %v", diario.ID, err)
+				continue
+			}
+
+			params = append(params, river.InsertManyParams{Args: args, InsertOpts: redriveInsertOpts()})
+		}
+	}
+
+	if len(params) == 0 {
+		log.Printf("✅ No stuck diarios to re-drive")
+		return nil
+	}
+
+	if _, err := client.InsertMany(ctx, params); err != nil {
+		return fmt.Errorf("failed to re-drive diarios: %w", err)
+	}
+
+	log.Printf("🔁 Re-drove %d diário(s)", len(params))
+	return nil
+}
+
+// nextStageArgs returns the args of the stage job that moves a diario on
+func nextStageArgs(diario *model.Diario, slug string) (river.JobArgs, error) {
+	switch diarios.ResumeStatus(diario) {
+	case diarios.StatusDiscovered:
+		if diario.DownloadURL == nil {
+			return nil, errors.New("no download URL")
+		}
+		return DownloadDiarioArgs{
+			DiarioID:       diario.ID,
+			Slug:           slug,
+			DownloadURL:    *diario.DownloadURL,
+			LastModifiedAt: diario.LastModifiedAt,
+		}, nil
+	case diarios.StatusDownloaded:
+		return ExtractDiarioArgs{DiarioID: diario.ID, Slug: slug, Version: diario.Version}, nil
+	case diarios.StatusExtracted:
+		return IndexDiarioArgs{DiarioID: diario.ID, Slug: slug, Version: diario.Version}, nil
+	default:
+		return nil, fmt.Errorf("nothing to re-drive in status %s", diario.Status)
+	}
+}
+
+// redriveInsertOpts makes re-driven stages unique by period as well as by args. The key
+// then differs from the one of the lost stage job, which is kept by River once completed
+// or discarded and would otherwise reject the insert.
+func redriveInsertOpts() *river.InsertOpts {
+	return &river.InsertOpts{
+		Queue:    "default",
+		Priority: 3,
+		UniqueOpts: river.UniqueOpts{
+			ByArgs:   true,
+			ByPeriod: redriveInterval,
+		},
+	}
+}
+
+// CreateRedriveDiariosPeriodicJob returns the periodic job re-driving stuck diarios
+func CreateRedriveDiariosPeriodicJob() *river.PeriodicJob {
+	return river.NewPeriodicJob(
+		river.PeriodicInterval(redriveInterval),
+
+		// Args constructor function
+		func() (river.JobArgs, *river.InsertOpts) {
+			return RedriveDiariosArgs{}, &river.InsertOpts{
+				Queue:    "default",
+				Priority: 3,
+			}
+		},
+
+		// Options - use nil for default options
+		nil,
+	)
+}
+
+// ScheduleRedriveDiariosJob schedules the re-drive of the diarios in status to run
+// immediately, regardless of how long they have been in it
+func ScheduleRedriveDiariosJob(ctx context.Context, client *river.Client[pgx.Tx], status string) error {
+	if !slices.Contains(redriveStatuses, status) && status != diarios.StatusFailed {
+		return fmt.Errorf("diarios cannot be re-driven from status %q", status)
+	}
+
+	_, err := client.Insert(ctx, RedriveDiariosArgs{Status: status}, &river.InsertOpts{
+		Queue:    "default",
+		Priority: 1,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule re-drive of %s diarios: %w", status, err)
+	}
+
+	log.Printf("✅ Scheduled immediate job to re-drive %s diarios", status)
+	return nil
+}
@@ -9,10 +9,30 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/riverqueue/river"
 	"github.com/riverqueue/river/riverdriver/riverpgxv5"
+	"github.com/riverqueue/river/rivertype"
+	"radaroficial.app/internal/atos"
 	"radaroficial.app/internal/diarios"
+	"radaroficial.app/internal/extract"
+	"radaroficial.app/internal/identifiers"
+	"radaroficial.app/internal/institutions"
 	"radaroficial.app/internal/storage"
+	"radaroficial.app/internal/vectorstore"
 )
 
+// pendingUniqueOpts dedupes a job by args while one is pending or running. Unlike the
+// default unique states, a completed or discarded job does not prevent running it again,
+// e.g. to retry a missing edition or a backfill.
+var pendingUniqueOpts = river.UniqueOpts{
+	ByArgs: true,
+	ByState: []rivertype.JobState{
+		rivertype.JobStateAvailable,
+		rivertype.JobStatePending,
+		rivertype.JobStateRetryable,
+		rivertype.JobStateRunning,
+		rivertype.JobStateScheduled,
+	},
+}
+
 // RiverClient wraps the River configuration and client
 type RiverClient struct {
 	Client   *river.Client[pgx.Tx]
@@ -21,8 +41,16 @@ type RiverClient struct {
 	StopFunc func(context.Context) error
 
 	// Worker instances
-	DiarioWorker       *DiarioWorker
-	GovernoPiauiWorker *GovernoPiauiWorker
+	FetchDiariosWorker     *FetchDiariosWorker
+	BackfillDiariosWorker  *BackfillDiariosWorker
+	BackfillEditionsWorker *BackfillEditionsWorker
+	FetchEditionWorker     *FetchEditionWorker
+	FillEditionGapsWorker  *FillEditionGapsWorker
+	DownloadDiarioWorker   *DownloadDiarioWorker
+	ExtractDiarioWorker    *ExtractDiarioWorker
+	IndexDiarioWorker      *IndexDiarioWorker
+	FinalizeDiarioWorker   *FinalizeDiarioWorker
+	RedriveDiariosWorker   *RedriveDiariosWorker
 }
 
 // NewRiverClient creates and configures a new River client and workers
@@ -32,34 +60,68 @@ func NewRiverClient(ctx context.Context, db *pgxpool.Pool) (*RiverClient, error)
 
 	// Create the job service dependencies
 	diarioService := diarios.NewInstitutionService(db)
+	institutionService := institutions.NewInstitutionService(db)
+	backfillService := diarios.NewBackfillService(db)
+	atoService := atos.NewAtoService(db)
+	identifierService := identifiers.NewIdentifierService(db)
+
+	// Initialize the blob store selected by STORAGE_BACKEND
+	store, err := storage.NewBlobStoreFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize blob store: %w", err)
+	}
 
-	// Initialize storage uploader
-	uploader, err := storage.NewSpacesUploader("radar-oficial-diarios-piaui")
+	// Initialize the PDF to Markdown converter
+	converter, err := extract.NewConverterFromEnv()
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize storage uploader: %w", err)
+		return nil, fmt.Errorf("failed to initialize PDF converter: %w", err)
 	}
 
+	// Initialize the vector store selected by VECTOR_STORE
+	vectorStore, err := vectorstore.NewVectorStoreFromEnv(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize vector store: %w", err)
+	}
+
+	ingester := diarios.NewIngester(diarioService, atoService, identifierService, store, converter, vectorStore)
+
 	// Create our job workers
-	diarioWorker := NewDiarioWorker(diarioService, uploader)
-	governoWorker := NewGovernoPiauiWorker(diarioService, uploader)
+	fetchWorker := NewFetchDiariosWorker(ingester, institutionService, backfillService)
+	backfillWorker := NewBackfillDiariosWorker(backfillService, institutionService)
+	backfillEditionsWorker := NewBackfillEditionsWorker(ingester, institutionService, backfillService)
+	editionWorker := NewFetchEditionWorker(ingester, institutionService)
+	gapsWorker := NewFillEditionGapsWorker(diarioService, institutionService)
+	downloadWorker := NewDownloadDiarioWorker(ingester)
+	extractWorker := NewExtractDiarioWorker(ingester)
+	indexWorker := NewIndexDiarioWorker(ingester, institutionService)
+	finalizeWorker := NewFinalizeDiarioWorker(ingester)
+	redriveWorker := NewRedriveDiariosWorker(diarioService, institutionService)
 
 	// Create a workers registry
 	workers := river.NewWorkers()
 
 	// Register all workers
-	river.AddWorker(workers, diarioWorker)
-	river.AddWorker(workers, governoWorker)
-
-	// Add periodic jobs
-	periodicJobs := []*river.PeriodicJob{
-		CreateDiarioDosMunicipiosPeriodicJob(),
-		CreateGovernoPiauiPeriodicJob(),
-	}
+	river.AddWorker(workers, fetchWorker)
+	river.AddWorker(workers, backfillWorker)
+	river.AddWorker(workers, backfillEditionsWorker)
+	river.AddWorker(workers, editionWorker)
+	river.AddWorker(workers, gapsWorker)
+	river.AddWorker(workers, downloadWorker)
+	river.AddWorker(workers, extractWorker)
+	river.AddWorker(workers, indexWorker)
+	river.AddWorker(workers, finalizeWorker)
+	river.AddWorker(workers, redriveWorker)
+
+	// Add one periodic job per registered diario source
+	periodicJobs := CreateFetchDiariosPeriodicJobs()
+	periodicJobs = append(periodicJobs, CreateFillEditionGapsPeriodicJobs()...)
+	periodicJobs = append(periodicJobs, CreateRedriveDiariosPeriodicJob())
 
 	// Create the River client config
 	riverConfig := river.Config{
 		Queues: map[string]river.QueueConfig{
-			"default": {MaxWorkers: 5},
+			"default":  {MaxWorkers: 5},
+			"backfill": {MaxWorkers: 2}, // keep the load on the gazette websites low
 		},
 		Workers:      workers,
 		PeriodicJobs: periodicJobs,
@@ -79,24 +141,42 @@ func NewRiverClient(ctx context.Context, db *pgxpool.Pool) (*RiverClient, error)
 	log.Printf("✅ River queue client started successfully")
 
 	return &RiverClient{
-		Client:             client,
-		RiverDB:            riverDB,
-		Workers:            workers,
-		StopFunc:           client.Stop,
-		DiarioWorker:       diarioWorker,
-		GovernoPiauiWorker: governoWorker,
+		Client:                 client,
+		RiverDB:                riverDB,
+		Workers:                workers,
+		StopFunc:               client.Stop,
+		FetchDiariosWorker:     fetchWorker,
+		BackfillDiariosWorker:  backfillWorker,
+		BackfillEditionsWorker: backfillEditionsWorker,
+		FetchEditionWorker:     editionWorker,
+		FillEditionGapsWorker:  gapsWorker,
+		DownloadDiarioWorker:   downloadWorker,
+		ExtractDiarioWorker:    extractWorker,
+		IndexDiarioWorker:      indexWorker,
+		FinalizeDiarioWorker:   finalizeWorker,
+		RedriveDiariosWorker:   redriveWorker,
 	}, nil
 }
 
+// NewInsertOnlyClient creates a River client that only enqueues jobs, for processes such
+// as the API that schedule jobs worked by the worker. It works no queue and is never
+// started.
+func NewInsertOnlyClient(db *pgxpool.Pool) (*river.Client[pgx.Tx], error) {
+	client, err := river.NewClient(riverpgxv5.New(db), &river.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create River client: %w", err)
+	}
+
+	return client, nil
+}
+
 // ScheduleInitialJobs sets up the initial job schedules when the system starts
 func (r *RiverClient) ScheduleInitialJobs(ctx context.Context) error {
 	// Schedule immediate jobs for testing
-	if err := ScheduleDiarioDosMunicipiosJob(ctx, r.Client); err != nil {
-		return fmt.Errorf("failed to schedule immediate diario job: %w", err)
-	}
-
-	if err := ScheduleGovernoPiauiJob(ctx, r.Client, ""); err != nil {
-		return fmt.Errorf("failed to schedule immediate governo job: %w", err)
+	for _, src := range diarios.Sources() {
+		if err := ScheduleFetchDiariosJob(ctx, r.Client, src.Metadata().Slug, ""); err != nil {
+			return err
+		}
 	}
 
 	log.Printf("✅ Initial jobs scheduled successfully")
@@ -0,0 +1,223 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScriptedResponse is a reply of the FakeServer to the conversations it matches
+type ScriptedResponse struct {
+	Match     string     `json:"match"` // Regular expression on the question, empty matches any
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"toolCalls,omitempty"`
+	Status    int        `json:"status,omitempty"` // Fails the request with this HTTP status
+}
+
+// Script is the list of replies of a FakeServer, the first matching one is used
+type Script struct {
+	Responses []ScriptedResponse `json:"responses"`
+}
+
+// FakeRequest is a request received by a FakeServer
+type FakeRequest struct {
+	Path       string          `json:"path"`
+	Body       json.RawMessage `json:"body"`
+	ReceivedAt time.Time       `json:"receivedAt"`
+}
+
+// FakeServer imitates the OpenAI chat completions API with scripted replies, streamed or
+// not, and the WhatsApp Cloud API messages endpoint, recording every request, so the
+// chat and WhatsApp flows run offline. See cmd/fakellm.
+type FakeServer struct {
+	responses []ScriptedResponse
+	matchers  []*regexp.Regexp
+
+	mu       sync.Mutex
+	requests []FakeRequest
+}
+
+// LoadScript reads a Script from a JSON file
+func LoadScript(path string) (Script, error) {
+	var script Script
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return script, fmt.Errorf("failed to read script: %w", err)
+	}
+
+	if err := json.Unmarshal(content, &script); err != nil {
+		return script, fmt.Errorf("failed to parse script %s: %w", path, err)
+	}
+
+	return script, nil
+}
+
+// NewFakeServer creates a FakeServer replying as script
+func NewFakeServer(script Script) (*FakeServer, error) {
+	s := &FakeServer{responses: script.Responses}
+
+	for _, response := range script.Responses {
+		matcher, err := regexp.Compile("(?i)" + response.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match %q: %w", response.Match, err)
+		}
+		s.matchers = append(s.matchers, matcher)
+	}
+
+	return s, nil
+}
+
+// Requests returns the requests received so far
+func (s *FakeServer) Requests() []FakeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]FakeRequest(nil), s.requests...)
+}
+
+// ServeHTTP serves POST .../chat/completions, POST .../messages and GET /requests, which
+// lists the recorded requests
+func (s *FakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "GET" && r.URL.Path == "/requests" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"requests": s.Requests()})
+		return
+	}
+
+	if r.Method != "POST" {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Error reading request", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, FakeRequest{Path: r.URL.Path, Body: body, ReceivedAt: time.Now()})
+	id := len(s.requests)
+	s.mu.Unlock()
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/chat/completions"):
+		s.complete(w, body, id)
+	case strings.HasSuffix(r.URL.Path, "/messages"):
+		log.Printf("📤 Fake WhatsApp message: %s", body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"messaging_product": "whatsapp",
+			"messages":          []map[string]string{{"id": fmt.Sprintf("wamid.fake-%d", id)}},
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *FakeServer) complete(w http.ResponseWriter, body []byte, id int) {
+	var req wireRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "Error parsing completion request", http.StatusBadRequest)
+		return
+	}
+
+	response := s.reply(req.Messages)
+	if response.Status >= 400 {
+		http.Error(w, "Scripted failure", response.Status)
+		return
+	}
+
+	finishReason := "stop"
+	if len(response.ToolCalls) > 0 {
+		finishReason = "tool_calls"
+	}
+
+	var calls []wireToolCall
+	for i, call := range response.ToolCalls {
+		if call.ID == "" {
+			call.ID = fmt.Sprintf("call_fake_%d_%d", id, i)
+		}
+		calls = append(calls, wireToolCall{
+			Index:    i,
+			ID:       call.ID,
+			Type:     "function",
+			Function: wireFunction{Name: call.Name, Arguments: call.Arguments},
+		})
+	}
+
+	if !req.Stream {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(wireResponse{Choices: []wireChoice{{
+			Message:      wireMessage{Role: RoleAssistant, Content: response.Content, ToolCalls: calls},
+			FinishReason: finishReason,
+		}}})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+
+	send := func(choice wireChoice) {
+		chunk, _ := json.Marshal(wireResponse{Choices: []wireChoice{choice}})
+		fmt.Fprintf(w, "data: %s\n\n", chunk)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	// Words are streamed one by one, with the space before them
+	for i, word := range strings.SplitAfter(response.Content, " ") {
+		if word == "" {
+			continue
+		}
+		delta := wireMessage{Content: word}
+		if i == 0 {
+			delta.Role = RoleAssistant
+		}
+		send(wireChoice{Delta: delta})
+	}
+
+	for _, call := range calls {
+		send(wireChoice{Delta: wireMessage{ToolCalls: []wireToolCall{call}}})
+	}
+
+	send(wireChoice{FinishReason: finishReason})
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// reply returns the first scripted response matching the question of the last user
+// message, the text after "Pergunta: " when the chat prompt has sources, or an echo
+func (s *FakeServer) reply(messages []wireMessage) ScriptedResponse {
+	question := ""
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == RoleUser {
+			question = messages[i].Content
+			break
+		}
+	}
+
+	// Sources quoted before the question must not select the reply
+	if _, after, ok := strings.Cut(question, "Pergunta: "); ok {
+		question = after
+	}
+
+	for i, matcher := range s.matchers {
+		if matcher.MatchString(question) {
+			return s.responses[i]
+		}
+	}
+
+	if runes := []rune(question); len(runes) > 80 {
+		question = string(runes[:80]) + "..."
+	}
+
+	return ScriptedResponse{Content: "Resposta simulada para: " + question}
+}
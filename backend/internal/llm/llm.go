@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+)
+
+const (
+	defaultBaseURL = "https://api.openai.com/v1"
+	defaultModel   = "gpt-4o-mini"
+)
+
+// Roles of the messages of a conversation
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Message is a turn of a conversation with the model
+type Message struct {
+	Role       string
+	Content    string
+	ToolCalls  []ToolCall // Requested by an assistant message
+	ToolCallID string     // Answered by a tool message
+}
+
+// Tool is a function the model may ask to call
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage // JSON schema of the arguments
+}
+
+// ToolCall is a call of a tool requested by the model
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON object
+}
+
+// Request is a conversation to complete
+type Request struct {
+	Messages    []Message
+	Tools       []Tool
+	Temperature *float64
+	MaxTokens   int
+}
+
+// Response is the completion of a conversation
+type Response struct {
+	Content      string
+	ToolCalls    []ToolCall
+	FinishReason string // stop, length or tool_calls
+}
+
+// Delta is an increment of a streamed completion: some content, or a tool call once its
+// arguments are complete
+type Delta struct {
+	Content  string
+	ToolCall *ToolCall
+}
+
+// LLMClient completes conversations with a model
+type LLMClient interface {
+	// Complete returns the whole completion of a conversation
+	Complete(ctx context.Context, req Request) (*Response, error)
+	// Stream calls onDelta as the completion is generated and returns it once done.
+	// An error from onDelta stops the stream and is returned.
+	Stream(ctx context.Context, req Request, onDelta func(Delta) error) (*Response, error)
+}
+
+var _ LLMClient = (*OpenAIClient)(nil)
+
+// NewLLMClientFromEnv creates a client of the OpenAI-compatible chat completions API at
+// LLM_BASE_URL with LLM_API_KEY and LLM_MODEL. Without LLM_BASE_URL, it falls back to
+// the DigitalOcean agent at DO_AGENT_PIAUI_URL, then to OpenAI with OPENAI_API_KEY. The
+// fake server of cmd/fakellm needs no key.
+func NewLLMClientFromEnv() (LLMClient, error) {
+	cfg := OpenAIConfig{
+		BaseURL: os.Getenv("LLM_BASE_URL"),
+		APIKey:  os.Getenv("LLM_API_KEY"),
+		Model:   os.Getenv("LLM_MODEL"),
+	}
+
+	switch {
+	case cfg.BaseURL != "":
+	case os.Getenv("DO_AGENT_PIAUI_URL") != "":
+		// The agent answers with its own model, the model field is ignored
+		cfg.BaseURL = strings.TrimSuffix(os.Getenv("DO_AGENT_PIAUI_URL"), "/") + "/api/v1"
+		if cfg.APIKey == "" {
+			cfg.APIKey = os.Getenv("DO_AGENT_PIAUI_ACCESS_KEY")
+		}
+	default:
+		cfg.BaseURL = defaultBaseURL
+		if cfg.APIKey == "" {
+			cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+		}
+	}
+
+	if cfg.APIKey == "" && cfg.BaseURL == defaultBaseURL {
+		return nil, errors.New("LLM_API_KEY environment variable not set")
+	}
+	if cfg.Model == "" {
+		cfg.Model = defaultModel
+	}
+
+	return NewOpenAIClient(cfg), nil
+}
@@ -0,0 +1,257 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// completeTimeout bounds a whole completion, streams are only bounded by their context
+const completeTimeout = 2 * time.Minute
+
+// OpenAIConfig configures an OpenAIClient
+type OpenAIConfig struct {
+	BaseURL string // Defaults to https://api.openai.com/v1
+	APIKey  string // Optional for local and fake servers
+	Model   string
+}
+
+// OpenAIClient completes conversations with the OpenAI chat completions API or any API
+// compatible with it
+type OpenAIClient struct {
+	config OpenAIConfig
+	client *http.Client
+}
+
+// NewOpenAIClient creates a new OpenAIClient
+func NewOpenAIClient(config OpenAIConfig) *OpenAIClient {
+	if config.BaseURL == "" {
+		config.BaseURL = defaultBaseURL
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+
+	return &OpenAIClient{config: config, client: &http.Client{}}
+}
+
+// Model returns the name of the model
+func (c *OpenAIClient) Model() string { return c.config.Model }
+
+// Wire format of the chat completions API
+
+type wireFunction struct {
+	Name        string          `json:"name,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+	Arguments   string          `json:"arguments,omitempty"`
+}
+
+type wireTool struct {
+	Type     string       `json:"type"`
+	Function wireFunction `json:"function"`
+}
+
+type wireToolCall struct {
+	Index    int          `json:"index"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function wireFunction `json:"function"`
+}
+
+type wireMessage struct {
+	Role       string         `json:"role,omitempty"`
+	Content    string         `json:"content"`
+	ToolCalls  []wireToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+}
+
+type wireRequest struct {
+	Model       string        `json:"model"`
+	Messages    []wireMessage `json:"messages"`
+	Tools       []wireTool    `json:"tools,omitempty"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Stream      bool          `json:"stream"`
+}
+
+type wireChoice struct {
+	Message      wireMessage `json:"message"`
+	Delta        wireMessage `json:"delta"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+type wireResponse struct {
+	Choices []wireChoice `json:"choices"`
+}
+
+func (c *OpenAIClient) wireRequest(req Request, stream bool) wireRequest {
+	w := wireRequest{
+		Model:       c.config.Model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		Stream:      stream,
+	}
+
+	for _, m := range req.Messages {
+		wm := wireMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+		for i, call := range m.ToolCalls {
+			wm.ToolCalls = append(wm.ToolCalls, wireToolCall{
+				Index:    i,
+				ID:       call.ID,
+				Type:     "function",
+				Function: wireFunction{Name: call.Name, Arguments: call.Arguments},
+			})
+		}
+		w.Messages = append(w.Messages, wm)
+	}
+
+	for _, t := range req.Tools {
+		w.Tools = append(w.Tools, wireTool{
+			Type:     "function",
+			Function: wireFunction{Name: t.Name, Description: t.Description, Parameters: t.Parameters},
+		})
+	}
+
+	return w
+}
+
+func (c *OpenAIClient) post(ctx context.Context, payload wireRequest) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call model: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		responseBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("model API error (status %d): %s", resp.StatusCode, bytes.TrimSpace(responseBody))
+	}
+
+	return resp, nil
+}
+
+// Complete returns the whole completion of a conversation
+func (c *OpenAIClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, completeTimeout)
+	defer cancel()
+
+	resp, err := c.post(ctx, c.wireRequest(req, false))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var completion wireResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return nil, fmt.Errorf("failed to parse model response: %w", err)
+	}
+
+	if len(completion.Choices) == 0 {
+		return nil, errors.New("no response from model")
+	}
+
+	choice := completion.Choices[0]
+	response := &Response{Content: choice.Message.Content, FinishReason: choice.FinishReason}
+	for _, call := range choice.Message.ToolCalls {
+		response.ToolCalls = append(response.ToolCalls, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+
+	return response, nil
+}
+
+// Stream reads the completion of a conversation as server-sent events. Tool calls are
+// passed to onDelta once the stream ends, their arguments arrive in fragments.
+func (c *OpenAIClient) Stream(ctx context.Context, req Request, onDelta func(Delta) error) (*Response, error) {
+	resp, err := c.post(ctx, c.wireRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	response := &Response{}
+	var content strings.Builder
+	var calls []*ToolCall
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk wireResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse model stream: %w", err)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			response.FinishReason = choice.FinishReason
+		}
+
+		for _, call := range choice.Delta.ToolCalls {
+			for len(calls) <= call.Index {
+				calls = append(calls, &ToolCall{})
+			}
+			if call.ID != "" {
+				calls[call.Index].ID = call.ID
+			}
+			calls[call.Index].Name += call.Function.Name
+			calls[call.Index].Arguments += call.Function.Arguments
+		}
+
+		if choice.Delta.Content != "" {
+			content.WriteString(choice.Delta.Content)
+			if err := onDelta(Delta{Content: choice.Delta.Content}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read model stream: %w", err)
+	}
+
+	response.Content = content.String()
+	for _, call := range calls {
+		response.ToolCalls = append(response.ToolCalls, *call)
+		if err := onDelta(Delta{ToolCall: call}); err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Ato is a single act (portaria, decreto, extrato...) published in a diario
+type Ato struct {
+	ID            int       `db:"id"`
+	DiarioID      int       `db:"diario_id"`
+	Seq           int       `db:"seq"`   // Position of the act within the diario, starting at 0
+	Orgao         *string   `db:"orgao"` // Issuing body, from the órgão header preceding the act
+	ActType       string    `db:"act_type"`
+	Number        *string   `db:"number"`
+	Year          *int      `db:"year"`
+	Title         string    `db:"title"`
+	Content       string    `db:"content"`
+	PageStart     int       `db:"page_start"`
+	PageEnd       int       `db:"page_end"`
+	OCR           bool      `db:"ocr"` // Whether any page of the act was OCR'd
+	OCRConfidence *float64  `db:"ocr_confidence"`
+	CreatedAt     time.Time `db:"created_at"`
+}
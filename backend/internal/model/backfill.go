@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// Backfill tracks a historical fetch of an institution's diarios over a date range
+type Backfill struct {
+	ID            int        `db:"id"`
+	InstitutionID int        `db:"institution_id"`
+	StartDate     time.Time  `db:"start_date"`
+	EndDate       time.Time  `db:"end_date"`
+	NextDate      time.Time  `db:"next_date"` // First day not yet enqueued, days are tracked in backfill_days
+	EnqueuedDays  int        `db:"enqueued_days"`
+	CompletedAt   *time.Time `db:"completed_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+	UpdatedAt     time.Time  `db:"updated_at"`
+}
@@ -3,13 +3,28 @@ package model
 import "time"
 
 type Diario struct {
-	ID                  int        `db:"id"`
-	InstitutionID       int        `db:"institution_id"`
-	PublishedAt         *time.Time `db:"published_at"`
-	LastModifiedAt      *time.Time `db:"last_modified_at"`
-	SourceURL           string     `db:"source_url"`
-	Description         *string    `db:"description"`
-	CreatedAt           time.Time  `db:"created_at"`
-	UpdatedAt           time.Time  `db:"updated_at"`
-	IndexingSubmittedAt *time.Time `db:"indexing_submitted_at"`
+	ID              int        `db:"id"`
+	InstitutionID   int        `db:"institution_id"`
+	PublishedAt     *time.Time `db:"published_at"`
+	LastModifiedAt  *time.Time `db:"last_modified_at"`
+	SourceURL       string     `db:"source_url"`
+	Description     *string    `db:"description"`
+	EditionNumber   *int       `db:"edition_number"`
+	SHA256          *string    `db:"sha256"`       // Hex SHA-256 of the PDF
+	SizeBytes       *int64     `db:"size_bytes"`   // Size of the PDF
+	ObjectKey       *string    `db:"object_key"`   // Key of the PDF in the blob store
+	PageCount       *int       `db:"page_count"`   // Pages of the PDF, known once extracted
+	Version         int        `db:"version"`      // Incremented each time the PDF is replaced at the source
+	Status          string     `db:"status"`       // Lifecycle status, see diarios.Status*
+	DownloadURL     *string    `db:"download_url"` // URL of the PDF at the source
+	StatusChangedAt *time.Time `db:"status_changed_at"`
+	DownloadedAt    *time.Time `db:"downloaded_at"`
+	ExtractedAt     *time.Time `db:"extracted_at"`
+	IndexedAt       *time.Time `db:"indexed_at"`
+	FailedAt        *time.Time `db:"failed_at"`
+	SupersededAt    *time.Time `db:"superseded_at"`
+	LastError       *string    `db:"last_error"` // Error of the last failed attempt of a stage
+	LastErrorAt     *time.Time `db:"last_error_at"`
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"`
 }
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// DiarioVersion is a previous version of a diario whose PDF was replaced at the source
+type DiarioVersion struct {
+	ID             int        `db:"id"`
+	DiarioID       int        `db:"diario_id"`
+	Version        int        `db:"version"`
+	SourceURL      *string    `db:"source_url"`
+	LastModifiedAt *time.Time `db:"last_modified_at"`
+	SHA256         *string    `db:"sha256"`
+	SizeBytes      *int64     `db:"size_bytes"`
+	ObjectKey      *string    `db:"object_key"`
+	SupersededAt   time.Time  `db:"superseded_at"`
+}
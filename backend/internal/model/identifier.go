@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Identifier is an exact identifier (process number, CNPJ, CPF...) cited by an ato
+type Identifier struct {
+	ID         int       `db:"id" json:"id"`
+	AtoID      int       `db:"ato_id" json:"atoId"`
+	DiarioID   int       `db:"diario_id" json:"diarioId"`
+	Kind       string    `db:"kind" json:"kind"`
+	Value      string    `db:"value" json:"value"`             // As published, CPFs masked as ***.456.789-**
+	Normalized string    `db:"normalized" json:"normalized"`   // Canonical form used for exact lookups
+	Amount     *float64  `db:"amount" json:"amount,omitempty"` // Set for monetary values
+	CreatedAt  time.Time `db:"created_at" json:"createdAt"`
+}
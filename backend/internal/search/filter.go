@@ -0,0 +1,179 @@
+package search
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"radaroficial.app/internal/atos"
+	"radaroficial.app/internal/model"
+)
+
+// Filter restricts retrieval to atos published in a period, by some institutions, about
+// some municipalities or of some types. The zero Filter matches every ato.
+type Filter struct {
+	From             *time.Time // Inclusive
+	To               *time.Time // Exclusive
+	InstitutionIDs   []int
+	InstitutionSlugs []string
+	State            string
+	Cities           []string // Normalized with NormalizeCity
+	ActTypes         []string // As segmented, e.g. portaria
+	DiarioID         int
+}
+
+// IsZero reports whether the filter matches every ato
+func (f Filter) IsZero() bool {
+	return f.From == nil && f.To == nil && len(f.InstitutionIDs) == 0 &&
+		len(f.InstitutionSlugs) == 0 && f.State == "" && len(f.Cities) == 0 &&
+		len(f.ActTypes) == 0 && f.DiarioID == 0
+}
+
+// Merge returns f completed with the criteria of other it does not set, so a selection
+// made in the UI wins over what was derived from the question
+func (f Filter) Merge(other Filter) Filter {
+	if f.From == nil && f.To == nil {
+		f.From, f.To = other.From, other.To
+	}
+	if len(f.InstitutionIDs) == 0 && len(f.InstitutionSlugs) == 0 {
+		f.InstitutionIDs, f.InstitutionSlugs = other.InstitutionIDs, other.InstitutionSlugs
+	}
+	if f.State == "" {
+		f.State = other.State
+	}
+	if len(f.Cities) == 0 {
+		f.Cities = other.Cities
+	}
+	if len(f.ActTypes) == 0 {
+		f.ActTypes = other.ActTypes
+	}
+	if f.DiarioID == 0 {
+		f.DiarioID = other.DiarioID
+	}
+	return f
+}
+
+// String describes the filter for logs
+func (f Filter) String() string {
+	var parts []string
+	if f.From != nil {
+		parts = append(parts, "from "+f.From.Format(time.DateOnly))
+	}
+	if f.To != nil {
+		parts = append(parts, "to "+f.To.Format(time.DateOnly))
+	}
+	if len(f.InstitutionIDs) > 0 {
+		parts = append(parts, fmt.Sprintf("institutions %v", f.InstitutionIDs))
+	}
+	if len(f.InstitutionSlugs) > 0 {
+		parts = append(parts, "institutions "+strings.Join(f.InstitutionSlugs, ", "))
+	}
+	if f.State != "" {
+		parts = append(parts, "state "+f.State)
+	}
+	if len(f.Cities) > 0 {
+		parts = append(parts, "cities "+strings.Join(f.Cities, ", "))
+	}
+	if len(f.ActTypes) > 0 {
+		parts = append(parts, "act types "+strings.Join(f.ActTypes, ", "))
+	}
+	if f.DiarioID != 0 {
+		parts = append(parts, fmt.Sprintf("diario %d", f.DiarioID))
+	}
+	if len(parts) == 0 {
+		return "no filter"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FilterFromQuery reads a filter selected in the UI from query parameters: from and to
+// (YYYY-MM-DD, both inclusive), institution (ID or slug, repeatable), state, city and
+// actType (repeatable) and diario (ID)
+func FilterFromQuery(values url.Values) (Filter, error) {
+	var f Filter
+
+	if v := values.Get("from"); v != "" {
+		from, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return f, fmt.Errorf("invalid from date %q", v)
+		}
+		f.From = &from
+	}
+
+	if v := values.Get("to"); v != "" {
+		to, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return f, fmt.Errorf("invalid to date %q", v)
+		}
+		// The whole last day is included
+		to = to.AddDate(0, 0, 1)
+		f.To = &to
+	}
+
+	for _, v := range values["institution"] {
+		if id, err := strconv.Atoi(v); err == nil {
+			f.InstitutionIDs = append(f.InstitutionIDs, id)
+		} else if v = strings.TrimSpace(v); v != "" {
+			f.InstitutionSlugs = append(f.InstitutionSlugs, v)
+		}
+	}
+
+	for _, v := range values["city"] {
+		if city := NormalizeCity(v); city != "" {
+			f.Cities = append(f.Cities, city)
+		}
+	}
+
+	for _, v := range values["actType"] {
+		if actType := strings.ToLower(strings.TrimSpace(v)); actType != "" {
+			f.ActTypes = append(f.ActTypes, actType)
+		}
+	}
+
+	f.State = strings.ToUpper(strings.TrimSpace(values.Get("state")))
+
+	if v := values.Get("diario"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid diario %q", v)
+		}
+		f.DiarioID = id
+	}
+
+	return f, nil
+}
+
+// CityOf returns the normalized municipality of an ato: the one of a municipal
+// institution, or the one named by the órgão header in diarios gathering several
+// municipalities
+func CityOf(institution *model.Institution, ato model.Ato) string {
+	if institution.City != nil {
+		return NormalizeCity(*institution.City)
+	}
+	if ato.Orgao != nil {
+		return NormalizeCity(atos.Municipality(*ato.Orgao))
+	}
+	return ""
+}
+
+// NormalizeCity returns the form municipalities are indexed and filtered by: uppercase,
+// without accents and with single spaces, e.g. "SAO JOAO DO PIAUI"
+func NormalizeCity(name string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(fold(name)), " "))
+}
+
+// fold removes accents from s
+func fold(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		return s
+	}
+	return folded
+}
@@ -0,0 +1,255 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"radaroficial.app/internal/model"
+)
+
+const (
+	// SnippetStart and SnippetStop surround the matched words of a snippet
+	SnippetStart = "<mark>"
+	SnippetStop  = "</mark>"
+
+	headlineOptions = "StartSel=" + SnippetStart + ", StopSel=" + SnippetStop +
+		", MaxFragments=3, MaxWords=30, MinWords=12, FragmentDelimiter=\" … \""
+)
+
+// FullTextService searches the text of the atos stored in Postgres
+type FullTextService struct {
+	DB *pgxpool.Pool
+}
+
+// NewFullTextService creates a new FullTextService
+func NewFullTextService(db *pgxpool.Pool) *FullTextService {
+	return &FullTextService{DB: db}
+}
+
+// Search returns the atos matching a query (see ParseQuery) and the filter, best ranked
+// first, with highlighted snippets, and the total number of matching atos
+func (s *FullTextService) Search(ctx context.Context, query string, filter Filter, limit, offset int) ([]Hit, int, error) {
+	args := []any{}
+
+	match, err := tsquery(ParseQuery(query), &args)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	where := filterConditions(filter, &args)
+
+	var total int
+	countQuery := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM atos a
+		JOIN diarios d ON d.id = a.diario_id
+		JOIN institutions i ON i.id = d.institution_id
+		WHERE a.tsv @@ %s AND %s
+	`, match, where)
+
+	if err := s.DB.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count matching atos: %w", err)
+	}
+
+	if total == 0 {
+		return []Hit{}, 0, nil
+	}
+
+	limitArg := "$" + strconv.Itoa(len(args)+1)
+	offsetArg := "$" + strconv.Itoa(len(args)+2)
+	args = append(args, limit, offset)
+
+	// Snippets are only built for the page of results, ts_headline reparses the content
+	searchQuery := fmt.Sprintf(`
+		WITH q AS (SELECT %[1]s AS query),
+		ranked AS (
+			SELECT a.id, ts_rank_cd(a.tsv, q.query, 32) AS rank
+			FROM atos a
+			JOIN diarios d ON d.id = a.diario_id
+			JOIN institutions i ON i.id = d.institution_id
+			CROSS JOIN q
+			WHERE a.tsv @@ q.query AND %[2]s
+			ORDER BY rank DESC, a.id DESC
+			LIMIT %[3]s OFFSET %[4]s
+		)
+		SELECT %[5]s, r.rank::float8, ts_headline('%[6]s', a.content, q.query, '%[7]s')
+		FROM ranked r
+		JOIN atos a ON a.id = r.id
+		JOIN diarios d ON d.id = a.diario_id
+		JOIN institutions i ON i.id = d.institution_id
+		CROSS JOIN q
+		ORDER BY r.rank DESC, a.id DESC
+	`, match, where, limitArg, offsetArg, hitColumns, TextSearchConfig, headlineOptions)
+
+	rows, err := s.DB.Query(ctx, searchQuery, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search atos: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []Hit{}
+	for rows.Next() {
+		h, err := scanHit(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		hits = append(hits, h)
+	}
+
+	return hits, total, rows.Err()
+}
+
+// Rank returns the limit atos best matching a query and the filter as hits holding only
+// the ID and rank of the ato, for fusion with another ranking. Unlike Search, matching
+// atos are not counted and no snippet is built, see Load.
+func (s *FullTextService) Rank(ctx context.Context, query string, filter Filter, limit int) ([]Hit, error) {
+	args := []any{}
+
+	match, err := tsquery(ParseQuery(query), &args)
+	if err != nil {
+		return nil, err
+	}
+
+	where := filterConditions(filter, &args)
+	args = append(args, limit)
+
+	rankQuery := fmt.Sprintf(`
+		WITH q AS (SELECT %[1]s AS query)
+		SELECT a.id, ts_rank_cd(a.tsv, q.query, 32)::float8 AS rank
+		FROM atos a
+		JOIN diarios d ON d.id = a.diario_id
+		JOIN institutions i ON i.id = d.institution_id
+		CROSS JOIN q
+		WHERE a.tsv @@ q.query AND %[2]s
+		ORDER BY rank DESC, a.id DESC
+		LIMIT $%[3]d
+	`, match, where, len(args))
+
+	rows, err := s.DB.Query(ctx, rankQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank atos: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []Hit{}
+	for rows.Next() {
+		var h Hit
+		if err := rows.Scan(&h.AtoID, &h.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan ato rank: %w", err)
+		}
+		hits = append(hits, h)
+	}
+
+	return hits, rows.Err()
+}
+
+// Load returns the atos of ids by ID, with snippets highlighting the words of query.
+// Atos removed since they were ranked are left out.
+func (s *FullTextService) Load(ctx context.Context, query string, ids []int) (map[int]Hit, error) {
+	args := []any{}
+
+	match, err := tsquery(ParseQuery(query), &args)
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, ids)
+
+	loadQuery := fmt.Sprintf(`
+		WITH q AS (SELECT %[1]s AS query)
+		SELECT %[2]s, 0::float8, ts_headline('%[3]s', a.content, q.query, '%[4]s')
+		FROM atos a
+		JOIN diarios d ON d.id = a.diario_id
+		JOIN institutions i ON i.id = d.institution_id
+		CROSS JOIN q
+		WHERE a.id = ANY($%[5]d)
+	`, match, hitColumns, TextSearchConfig, headlineOptions, len(args))
+
+	rows, err := s.DB.Query(ctx, loadQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load atos: %w", err)
+	}
+	defer rows.Close()
+
+	hits := map[int]Hit{}
+	for rows.Next() {
+		h, err := scanHit(rows)
+		if err != nil {
+			return nil, err
+		}
+		hits[h.AtoID] = h
+	}
+
+	return hits, rows.Err()
+}
+
+// hitColumns selects the fields of a Hit from atos a, diarios d and institutions i, in
+// the order read by scanHit, which also reads the score and the snippet that follow them
+const hitColumns = `
+	a.id, a.diario_id, i.id, i.name, i.state, i.city, a.orgao,
+	COALESCE(d.description, ''), d.edition_number, d.published_at, COALESCE(d.source_url, ''),
+	a.act_type, a.title, a.content, a.page_start, a.page_end`
+
+func scanHit(rows pgx.Rows) (Hit, error) {
+	var h Hit
+	var city, orgao *string
+	if err := rows.Scan(
+		&h.AtoID, &h.DiarioID, &h.InstitutionID, &h.Institution, &h.State, &city, &orgao,
+		&h.Description, &h.EditionNumber, &h.PublishedAt, &h.SourceURL,
+		&h.ActType, &h.Title, &h.Content, &h.Page, &h.PageEnd, &h.Score, &h.Snippet,
+	); err != nil {
+		return h, fmt.Errorf("failed to scan ato: %w", err)
+	}
+
+	h.City = CityOf(&model.Institution{City: city}, model.Ato{Orgao: orgao})
+	h.PageURL = PageURL(h.SourceURL, h.Page)
+	return h, nil
+}
+
+// filterConditions translates a filter to SQL conditions over atos a, diarios d and
+// institutions i, appending their values to args
+func filterConditions(filter Filter, args *[]any) string {
+	conditions := []string{"d.status <> 'superseded'"}
+
+	arg := func(v any) string {
+		*args = append(*args, v)
+		return "$" + strconv.Itoa(len(*args))
+	}
+
+	if filter.From != nil {
+		conditions = append(conditions, "d.published_at >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "d.published_at < "+arg(*filter.To))
+	}
+	if len(filter.InstitutionIDs) > 0 {
+		conditions = append(conditions, "d.institution_id = ANY("+arg(filter.InstitutionIDs)+")")
+	}
+	if len(filter.InstitutionSlugs) > 0 {
+		conditions = append(conditions, "i.slug = ANY("+arg(filter.InstitutionSlugs)+")")
+	}
+	if filter.State != "" {
+		conditions = append(conditions, "i.state = "+arg(filter.State))
+	}
+	if len(filter.ActTypes) > 0 {
+		conditions = append(conditions, "a.act_type = ANY("+arg(filter.ActTypes)+")")
+	}
+	if len(filter.Cities) > 0 {
+		// Municipal institutions have a city, diarios gathering municipalities name them in
+		// the órgão header, as CityOf
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM unnest(`+arg(filter.Cities)+`::text[]) AS c(city)
+			WHERE upper(unaccent(COALESCE(i.city, ''))) = c.city
+				OR (i.city IS NULL AND upper(unaccent(COALESCE(a.orgao, ''))) LIKE '%MUNICIPAL DE ' || c.city || '%')
+		)`)
+	}
+	if filter.DiarioID != 0 {
+		conditions = append(conditions, "a.diario_id = "+arg(filter.DiarioID))
+	}
+
+	return strings.Join(conditions, " AND ")
+}
@@ -0,0 +1,87 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+const snippetWords = 30
+
+// Highlight builds a snippet of content around the first word matching query, as
+// ts_headline does for keyword hits, for atos found only by meaning. Matched words are
+// put between SnippetStart and SnippetStop.
+func Highlight(content, query string) string {
+	var words, prefixes []string
+	for _, term := range ParseQuery(query) {
+		if term.Negated {
+			continue
+		}
+		for _, word := range strings.Fields(term.Text) {
+			if word = normalizeWord(word); word == "" {
+				continue
+			}
+			if term.Prefix {
+				prefixes = append(prefixes, word)
+			} else {
+				words = append(words, word)
+			}
+		}
+	}
+
+	matches := func(field string) bool {
+		field = normalizeWord(field)
+		if field == "" {
+			return false
+		}
+		for _, w := range words {
+			if field == w {
+				return true
+			}
+		}
+		for _, p := range prefixes {
+			if strings.HasPrefix(field, p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	fields := strings.Fields(content)
+
+	start := 0
+	for i, field := range fields {
+		if matches(field) {
+			start = max(i-snippetWords/3, 0)
+			break
+		}
+	}
+	end := min(start+snippetWords, len(fields))
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("… ")
+	}
+	for i := start; i < end; i++ {
+		if i > start {
+			b.WriteByte(' ')
+		}
+		if matches(fields[i]) {
+			b.WriteString(SnippetStart + fields[i] + SnippetStop)
+		} else {
+			b.WriteString(fields[i])
+		}
+	}
+	if end < len(fields) {
+		b.WriteString(" …")
+	}
+
+	return b.String()
+}
+
+// normalizeWord lowercases a word without accents and surrounding punctuation
+func normalizeWord(word string) string {
+	word = strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.ToLower(fold(word))
+}
@@ -0,0 +1,36 @@
+package search
+
+import (
+	"strconv"
+	"time"
+)
+
+// Hit is an indexed ato matching a query, with the metadata needed to cite it
+type Hit struct {
+	AtoID         int        `json:"atoId"`
+	DiarioID      int        `json:"diarioId"`
+	InstitutionID int        `json:"institutionId"`
+	Institution   string     `json:"institution"` // Name of the institution
+	State         string     `json:"state"`
+	City          string     `json:"city,omitempty"`
+	Description   string     `json:"description"` // Description of the diario
+	EditionNumber *int       `json:"editionNumber,omitempty"`
+	PublishedAt   *time.Time `json:"publishedAt,omitempty"`
+	SourceURL     string     `json:"sourceUrl"`
+	PageURL       string     `json:"pageUrl,omitempty"` // SourceURL anchored at Page
+	ActType       string     `json:"actType"`
+	Title         string     `json:"title"`
+	Content       string     `json:"content"`
+	Snippet       string     `json:"snippet,omitempty"` // Matched words between SnippetStart and SnippetStop
+	Page          int        `json:"page"`
+	PageEnd       int        `json:"pageEnd"`
+	Score         float64    `json:"score"`
+}
+
+// PageURL links to a page of the stored PDF of a diario, viewers open it at the page
+func PageURL(sourceURL string, page int) string {
+	if sourceURL == "" || page < 1 {
+		return sourceURL
+	}
+	return sourceURL + "#page=" + strconv.Itoa(page)
+}
@@ -0,0 +1,149 @@
+package search
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// MaxHybridResults bounds how deep hybrid results can be paginated, both rankings
+	// are fetched down to the requested page
+	MaxHybridResults = 500
+
+	hybridRRFK = 60
+)
+
+// Retriever returns the atos semantically closest to a query, such as a
+// vectorstore.VectorStore
+type Retriever interface {
+	Search(ctx context.Context, query string, filter Filter, limit int) ([]Hit, error)
+}
+
+// HybridService ranks atos by both the words of a query and its meaning
+type HybridService struct {
+	FullText *FullTextService
+	Semantic Retriever // Optional, keyword only without it
+}
+
+// NewHybridService creates a new HybridService
+func NewHybridService(db *pgxpool.Pool, semantic Retriever) *HybridService {
+	return &HybridService{FullText: NewFullTextService(db), Semantic: semantic}
+}
+
+// Search returns the limit atos after offset best matching query and filter, fusing
+// the keyword and semantic rankings by reciprocal rank, and whether more atos follow.
+// Every hit has a highlighted snippet and a link to its page.
+func (s *HybridService) Search(ctx context.Context, query string, filter Filter, limit, offset int) ([]Hit, bool, error) {
+	return s.SearchQuestion(ctx, query, query, filter, limit, offset)
+}
+
+// SearchQuestion is Search with distinct queries per ranking: the keywords of a question,
+// such as rewritten by the chat, for the full-text ranking and the question in natural
+// language for the semantic ranking, as embeddings capture the meaning of whole sentences
+func (s *HybridService) SearchQuestion(ctx context.Context, question, keywords string, filter Filter, limit, offset int) ([]Hit, bool, error) {
+	if offset >= MaxHybridResults {
+		return []Hit{}, false, nil
+	}
+
+	// One more than the page tells whether another page follows
+	depth := min(offset+limit+1, MaxHybridResults)
+
+	keyword, err := s.FullText.Rank(ctx, keywords, filter, depth)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var semantic []Hit
+	if s.Semantic != nil {
+		semantic, err = s.Semantic.Search(ctx, question, filter, depth)
+		if err != nil {
+			log.Printf("⚠️ Semantic search failed, using keyword results only: %v", err)
+			semantic = nil
+		}
+	}
+
+	fused := fuse(semantic, keyword)
+	more := len(fused) > offset+limit && offset+limit < MaxHybridResults
+
+	if offset >= len(fused) {
+		return []Hit{}, false, nil
+	}
+	fused = fused[offset:min(offset+limit, len(fused))]
+
+	// Only the page of results is loaded and highlighted, keyword hits being bare IDs
+	ids := make([]int, 0, len(fused))
+	for _, hit := range fused {
+		if hit.AtoID != 0 {
+			ids = append(ids, hit.AtoID)
+		}
+	}
+
+	loaded, err := s.FullText.Load(ctx, keywords, ids)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hits := make([]Hit, 0, len(fused))
+	for _, hit := range fused {
+		if stored, ok := loaded[hit.AtoID]; ok {
+			stored.Score = hit.Score
+			hit = stored
+		} else if hit.Content == "" {
+			// Ranked by keyword, then removed
+			continue
+		}
+
+		if hit.Snippet == "" {
+			hit.Snippet = Highlight(hit.Content, keywords)
+		}
+		hit.PageURL = PageURL(hit.SourceURL, hit.Page)
+		hits = append(hits, hit)
+	}
+
+	return hits, more, nil
+}
+
+// fuse merges rankings by reciprocal rank fusion, keeping the first hit of an ato found
+// by several, which should be the one of the ranking carrying the most fields
+func fuse(rankings ...[]Hit) []Hit {
+	var fused []Hit
+	index := map[string]int{}
+
+	for _, ranking := range rankings {
+		for rank, hit := range ranking {
+			score := 1 / float64(hybridRRFK+rank+1)
+
+			key := hitKey(hit)
+			if i, ok := index[key]; ok {
+				fused[i].Score += score
+				continue
+			}
+
+			hit.Score = score
+			index[key] = len(fused)
+			fused = append(fused, hit)
+		}
+	}
+
+	sort.SliceStable(fused, func(i, j int) bool {
+		if fused[i].Score != fused[j].Score {
+			return fused[i].Score > fused[j].Score
+		}
+		return fused[i].AtoID > fused[j].AtoID
+	})
+
+	return fused
+}
+
+// hitKey identifies the ato of a hit, by position in its diario when the index did
+// not store its ID
+func hitKey(hit Hit) string {
+	if hit.AtoID != 0 {
+		return strconv.Itoa(hit.AtoID)
+	}
+	return strconv.Itoa(hit.DiarioID) + "/" + strconv.Itoa(hit.Page) + "/" + hit.Title
+}
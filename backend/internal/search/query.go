@@ -0,0 +1,139 @@
+package search
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TextSearchConfig is the Postgres text search configuration of atos.tsv: Portuguese
+// stemming over unaccented words
+const TextSearchConfig = "public.portuguese_unaccent"
+
+// ErrEmptyQuery is returned when a query has no term to search
+var ErrEmptyQuery = errors.New("empty search query")
+
+// Term is a single criterion of a full-text query
+type Term struct {
+	Text    string
+	Phrase  bool // "nomeação de servidor": the words in sequence
+	Prefix  bool // nomea*: words starting with Text
+	Negated bool // -revogação: atos without the term
+	Or      bool // Joined to the previous term by OR instead of AND
+}
+
+// ParseQuery reads a full-text query as typed by users: words are all required,
+// "quoted text" is a phrase, a trailing * searches a prefix, a leading - excludes a
+// term and OR (or "ou") between terms accepts either
+func ParseQuery(q string) []Term {
+	var terms []Term
+	or := false
+
+	runes := []rune(q)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		negated := false
+		if runes[i] == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			negated = true
+			i++
+		}
+
+		var term Term
+		if runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			term = Term{Text: strings.TrimSpace(string(runes[i+1 : end])), Phrase: true}
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '"' {
+				end++
+			}
+			word := string(runes[i:end])
+			i = end
+
+			if !negated && (word == "OR" || strings.EqualFold(word, "ou")) {
+				or = len(terms) > 0
+				continue
+			}
+
+			term = Term{Text: strings.TrimRight(word, "*"), Prefix: strings.HasSuffix(word, "*")}
+		}
+
+		if term.Text == "" {
+			continue
+		}
+
+		term.Negated = negated
+		term.Or = or
+		or = false
+		terms = append(terms, term)
+	}
+
+	return terms
+}
+
+// tsquery returns the SQL expression matching terms, appending their values to args
+func tsquery(terms []Term, args *[]any) (string, error) {
+	var b strings.Builder
+
+	arg := func(v any) string {
+		*args = append(*args, v)
+		return "$" + strconv.Itoa(len(*args))
+	}
+
+	n := 0
+	for _, term := range terms {
+		var expr string
+		switch {
+		case term.Phrase:
+			expr = "phraseto_tsquery('" + TextSearchConfig + "', " + arg(term.Text) + ")"
+		case term.Prefix:
+			lexeme := prefixLexeme(term.Text)
+			if lexeme == "" {
+				continue
+			}
+			expr = "to_tsquery('" + TextSearchConfig + "', " + arg(lexeme+":*") + ")"
+		default:
+			expr = "plainto_tsquery('" + TextSearchConfig + "', " + arg(term.Text) + ")"
+		}
+
+		if term.Negated {
+			expr = "!!" + expr
+		}
+
+		if n > 0 {
+			if term.Or {
+				b.WriteString(" || ")
+			} else {
+				b.WriteString(" && ")
+			}
+		}
+		b.WriteString(expr)
+		n++
+	}
+
+	if n == 0 {
+		return "", ErrEmptyQuery
+	}
+
+	return "(" + b.String() + ")", nil
+}
+
+// prefixLexeme keeps the letters and digits of a prefix, the only characters safe
+// inside to_tsquery
+func prefixLexeme(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,160 @@
+package search
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"radaroficial.app/internal/model"
+)
+
+var months = map[string]time.Month{
+	"janeiro": time.January, "fevereiro": time.February, "marco": time.March,
+	"abril": time.April, "maio": time.May, "junho": time.June, "julho": time.July,
+	"agosto": time.August, "setembro": time.September, "outubro": time.October,
+	"novembro": time.November, "dezembro": time.December,
+}
+
+// Patterns run on the question lowercased and without accents
+var (
+	dateRegexp         = regexp.MustCompile(`(?:^|[^\d./])(\d{1,2})/(\d{1,2})/(\d{4})\b`)
+	monthYearRegexp    = regexp.MustCompile(`\b(janeiro|fevereiro|marco|abril|maio|junho|julho|agosto|setembro|outubro|novembro|dezembro)\s+(?:de\s+)?(\d{4})\b`)
+	yearRegexp         = regexp.MustCompile(`\b(?:em|durante|no ano de|ao longo de)\s+(\d{4})\b`)
+	cityRegexp         = regexp.MustCompile(`\b(?:prefeitura|camara|municipio|cidade)(?:\s+municipal)?\s+de\s+([a-z][a-z' ]*)`)
+	doeRegexp          = regexp.MustCompile(`\bdoe[- ]?([a-z]{2})\b`)
+	municipalDiarioRef = regexp.MustCompile(`\bdiario (?:oficial )?dos municipios\b`)
+)
+
+// Words ending a municipality name taken from the question
+var cityStopWords = map[string]bool{
+	"em": true, "no": true, "na": true, "nos": true, "nas": true, "e": true, "ou": true,
+	"sobre": true, "para": true, "que": true, "com": true, "entre": true, "desde": true,
+	"ate": true, "publicado": true, "publicada": true, "publicados": true, "publicadas": true,
+	"neste": true, "nesta": true, "este": true, "esta": true, "ano": true, "mes": true,
+	"hoje": true, "ontem": true,
+}
+
+// Connectors allowed inside a municipality name, e.g. "SAO JOAO DO PIAUI"
+var cityConnectors = map[string]bool{"de": true, "da": true, "do": true, "das": true, "dos": true}
+
+// maxCityWords bounds the length of a municipality name taken from the question
+const maxCityWords = 5
+
+// ParseQuestion derives a filter from a question: a publication period ("15/03/2025",
+// "março de 2025", "em 2025", "hoje", "ontem"), the institutions it names by name, slug
+// or DOE acronym, and the municipalities of "prefeitura de X" or "município de X".
+// Relative dates are resolved against now.
+func ParseQuestion(question string, institutions []model.Institution, now time.Time) Filter {
+	q := strings.ToLower(fold(question))
+
+	var f Filter
+	f.From, f.To = parsePeriod(q, now)
+	f.InstitutionIDs = matchInstitutions(q, institutions)
+	f.Cities = matchCities(q)
+
+	return f
+}
+
+// parsePeriod returns the first period mentioned by the question, most precise first
+func parsePeriod(q string, now time.Time) (*time.Time, *time.Time) {
+	if m := dateRegexp.FindStringSubmatch(q); m != nil {
+		day, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		year, _ := strconv.Atoi(m[3])
+
+		if month >= 1 && month <= 12 && day >= 1 && day <= 31 {
+			from := time.Date(year, time.Month(month), day, 0, 0, 0, 0, now.Location())
+			// Reject days overflowing into the next month, e.g. 31/02
+			if from.Day() == day {
+				return period(from, from.AddDate(0, 0, 1))
+			}
+		}
+	}
+
+	if m := monthYearRegexp.FindStringSubmatch(q); m != nil {
+		year, _ := strconv.Atoi(m[2])
+		from := time.Date(year, months[m[1]], 1, 0, 0, 0, 0, now.Location())
+		return period(from, from.AddDate(0, 1, 0))
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case containsWord(q, "hoje"):
+		return period(today, today.AddDate(0, 0, 1))
+	case containsWord(q, "ontem"):
+		return period(today.AddDate(0, 0, -1), today)
+	}
+
+	if m := yearRegexp.FindStringSubmatch(q); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		from := time.Date(year, time.January, 1, 0, 0, 0, 0, now.Location())
+		return period(from, from.AddDate(1, 0, 0))
+	}
+
+	return nil, nil
+}
+
+func period(from, to time.Time) (*time.Time, *time.Time) {
+	return &from, &to
+}
+
+// matchInstitutions returns the IDs of the institutions named by the question
+func matchInstitutions(q string, institutions []model.Institution) []int {
+	var ids []int
+
+	for _, i := range institutions {
+		name := strings.ToLower(fold(i.Name))
+
+		matched := strings.Contains(q, name) || containsWord(q, i.Slug)
+
+		// "DOE-PI" names the diário of the state government
+		if m := doeRegexp.FindStringSubmatch(q); m != nil && i.Type == "state" && strings.EqualFold(m[1], i.State) {
+			matched = true
+		}
+
+		if municipalDiarioRef.MatchString(q) && i.Type == "municipal" {
+			matched = true
+		}
+
+		if matched {
+			ids = append(ids, i.ID)
+		}
+	}
+
+	return ids
+}
+
+// containsWord reports whether q contains word not as part of a longer word
+func containsWord(q, word string) bool {
+	if word == "" {
+		return false
+	}
+	return regexp.MustCompile(`\b` + regexp.QuoteMeta(word) + `\b`).MatchString(q)
+}
+
+// matchCities returns the municipalities named by the question, normalized
+func matchCities(q string) []string {
+	var cities []string
+
+	for _, m := range cityRegexp.FindAllStringSubmatch(q, -1) {
+		var words []string
+		for _, word := range strings.Fields(m[1]) {
+			if cityStopWords[word] || len(words) == maxCityWords {
+				break
+			}
+			words = append(words, word)
+		}
+
+		// A name does not end with a connector, e.g. "picos de" in "picos de 2025"
+		for len(words) > 0 && cityConnectors[words[len(words)-1]] {
+			words = words[:len(words)-1]
+		}
+
+		if len(words) > 0 {
+			cities = append(cities, NormalizeCity(strings.Join(words, " ")))
+		}
+	}
+
+	return cities
+}
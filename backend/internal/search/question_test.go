@@ -0,0 +1,87 @@
+package search
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"radaroficial.app/internal/model"
+)
+
+func TestParseQuestionPeriod(t *testing.T) {
+	now := time.Date(2025, time.March, 20, 15, 30, 0, 0, time.UTC)
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		question string
+		from, to time.Time
+	}{
+		{"nomeações de 15/03/2025", day(2025, time.March, 15), day(2025, time.March, 16)},
+		{"portarias de março de 2025", day(2025, time.March, 1), day(2025, time.April, 1)},
+		{"licitações em 2024", day(2024, time.January, 1), day(2025, time.January, 1)},
+		{"o que foi publicado hoje?", day(2025, time.March, 20), day(2025, time.March, 21)},
+		{"Diário de ontem", day(2025, time.March, 19), day(2025, time.March, 20)},
+		// A date is more precise than a relative day
+		{"ontem ou 10/01/2025", day(2025, time.January, 10), day(2025, time.January, 11)},
+	}
+
+	for _, tt := range tests {
+		f := ParseQuestion(tt.question, nil, now)
+		if f.From == nil || f.To == nil {
+			t.Errorf("ParseQuestion(%q) has no period", tt.question)
+			continue
+		}
+		if !f.From.Equal(tt.from) || !f.To.Equal(tt.to) {
+			t.Errorf("ParseQuestion(%q) period = %s - %s, want %s - %s", tt.question, f.From, f.To, tt.from, tt.to)
+		}
+	}
+}
+
+func TestParseQuestionWithoutPeriod(t *testing.T) {
+	now := time.Date(2025, time.March, 20, 15, 30, 0, 0, time.UTC)
+
+	questions := []string{
+		"quem foi contemplado no edital?",
+		"servidores com contemplação de férias",
+		"hojeriza", // not a relative day either
+		"data 31/02/2025",
+		"qual a portaria de nomeação?",
+	}
+
+	for _, question := range questions {
+		if f := ParseQuestion(question, nil, now); f.From != nil || f.To != nil {
+			t.Errorf("ParseQuestion(%q) period = %s - %s, want none", question, f.From, f.To)
+		}
+	}
+}
+
+func TestParseQuestionInstitutionsAndCities(t *testing.T) {
+	institutions := []model.Institution{
+		{ID: 1, Name: "Governo do Estado do Piauí", Slug: "governo-pi", Type: "state", State: "PI"},
+		{ID: 2, Name: "Diário Oficial dos Municípios do Piauí", Slug: "municipios-pi", Type: "municipal", State: "PI"},
+	}
+	now := time.Date(2025, time.March, 20, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		question string
+		ids      []int
+		cities   []string
+	}{
+		{"decretos do DOE-PI", []int{1}, nil},
+		{"licitações no diário oficial dos municípios", []int{2}, nil},
+		{"nomeações da prefeitura de São João do Piauí em 2025", nil, []string{"SAO JOAO DO PIAUI"}},
+		{"contratos do município de Picos de 2025", nil, []string{"PICOS"}},
+	}
+
+	for _, tt := range tests {
+		f := ParseQuestion(tt.question, institutions, now)
+		if !slices.Equal(f.InstitutionIDs, tt.ids) {
+			t.Errorf("ParseQuestion(%q) institutions = %v, want %v", tt.question, f.InstitutionIDs, tt.ids)
+		}
+		if !slices.Equal(f.Cities, tt.cities) {
+			t.Errorf("ParseQuestion(%q) cities = %v, want %v", tt.question, f.Cities, tt.cities)
+		}
+	}
+}
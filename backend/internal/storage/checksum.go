@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrChecksumMismatch is returned when an object read back does not match its recorded checksum
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Checksum returns the hex SHA-256 and the size of content
+func Checksum(content []byte) (string, int64) {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), int64(len(content))
+}
+
+// ContentKey returns the content-addressed key of a file, e.g. "sha256/ab/ab12....pdf",
+// so identical files share a single object whatever their name or description
+func ContentKey(sha256Hex, ext string) string {
+	return fmt.Sprintf("sha256/%s/%s%s", sha256Hex[:2], sha256Hex, ext)
+}
+
+// PutContent stores content under its content-addressed key, skipping the upload when an
+// object of the same size is already there, and returns the key with the checksum
+func PutContent(ctx context.Context, store BlobStore, content []byte, ext, contentType string) (key, sha256Hex string, size int64, err error) {
+	sha256Hex, size = Checksum(content)
+	key = ContentKey(sha256Hex, ext)
+
+	info, err := store.Stat(ctx, key)
+	switch {
+	case err == nil && info.Size == size:
+		return key, sha256Hex, size, nil
+	case err != nil && !errors.Is(err, ErrNotFound):
+		return "", "", 0, err
+	}
+
+	if err := store.Put(ctx, key, bytes.NewReader(content), size, contentType); err != nil {
+		return "", "", 0, err
+	}
+
+	return key, sha256Hex, size, nil
+}
+
+// ReadVerified reads an object back and checks it against the recorded checksum and size.
+// An empty sha256Hex or a negative size skips the respective check, for legacy objects.
+func ReadVerified(ctx context.Context, store BlobStore, key, sha256Hex string, size int64) ([]byte, error) {
+	r, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+
+	actualSHA256, actualSize := Checksum(content)
+
+	if size >= 0 && actualSize != size {
+		return nil, fmt.Errorf("%w: %s has %d bytes, expected %d", ErrChecksumMismatch, key, actualSize, size)
+	}
+
+	if sha256Hex != "" && actualSHA256 != sha256Hex {
+		return nil, fmt.Errorf("%w: %s has sha256 %s, expected %s", ErrChecksumMismatch, key, actualSHA256, sha256Hex)
+	}
+
+	return content, nil
+}
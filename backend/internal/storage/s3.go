@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config configures an S3Store
+type S3Config struct {
+	Endpoint     string // Host and optional port, e.g. "nyc3.digitaloceanspaces.com" or "localhost:9000"
+	AccessKey    string
+	SecretKey    string
+	Region       string
+	Bucket       string
+	Secure       bool   // Use HTTPS. Disable for a local MinIO.
+	PublicURL    string // Base URL of the public objects, defaults to the bucket URL on the endpoint
+	CreateBucket bool   // Create the bucket when missing, for local MinIO instances
+}
+
+// S3Store stores objects in an S3-compatible bucket
+type S3Store struct {
+	Client    *minio.Client
+	Bucket    string
+	publicURL string
+}
+
+// NewS3Store creates an S3Store
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("missing S3 endpoint")
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.Secure,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to init S3 client: %w", err)
+	}
+
+	publicURL := strings.TrimSuffix(cfg.PublicURL, "/")
+	if publicURL == "" {
+		if cfg.Secure {
+			// Virtual-hosted style, as served by Spaces
+			publicURL = fmt.Sprintf("https://%s.%s", cfg.Bucket, cfg.Endpoint)
+		} else {
+			// Path style, as served by MinIO
+			publicURL = fmt.Sprintf("http://%s/%s", cfg.Endpoint, cfg.Bucket)
+		}
+	}
+
+	if cfg.CreateBucket {
+		ctx := context.Background()
+		exists, err := client.BucketExists(ctx, cfg.Bucket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check bucket %s: %w", cfg.Bucket, err)
+		}
+
+		if !exists {
+			if err := client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region}); err != nil {
+				return nil, fmt.Errorf("failed to create bucket %s: %w", cfg.Bucket, err)
+			}
+			log.Printf("🪣 Created bucket %s", cfg.Bucket)
+		}
+	}
+
+	return &S3Store{
+		Client:    client,
+		Bucket:    cfg.Bucket,
+		publicURL: publicURL,
+	}, nil
+}
+
+// Put uploads content from a reader with known size
+func (s *S3Store) Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	_, err := s.Client.PutObject(ctx, s.Bucket, key, content, size, minio.PutObjectOptions{
+		ContentType:  contentType,
+		UserMetadata: map[string]string{"x-amz-acl": "public-read"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload to %s: %w", s.Bucket, err)
+	}
+
+	log.Printf("✅ Uploaded to %s/%s", s.Bucket, key)
+	return nil
+}
+
+// Get opens the object stored under key
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	// GetObject is lazy, stat first so missing objects fail here
+	if _, err := s.Stat(ctx, key); err != nil {
+		return nil, err
+	}
+
+	object, err := s.Client.GetObject(ctx, s.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+
+	return object, nil
+}
+
+// Stat returns the metadata of the object stored under key
+func (s *S3Store) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	info, err := s.Client.StatObject(ctx, s.Bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return ObjectInfo{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return ObjectInfo{}, fmt.Errorf("failed to stat %s: %w", key, err)
+	}
+
+	return ObjectInfo{
+		Key:          info.Key,
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		LastModified: info.LastModified,
+	}, nil
+}
+
+// Delete removes the object stored under key
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := s.Client.RemoveObject(ctx, s.Bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// List returns the objects whose key starts with prefix
+func (s *S3Store) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+
+	for info := range s.Client.ListObjects(ctx, s.Bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if info.Err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", prefix, info.Err)
+		}
+
+		objects = append(objects, ObjectInfo{
+			Key:          info.Key,
+			Size:         info.Size,
+			ContentType:  info.ContentType,
+			LastModified: info.LastModified,
+		})
+	}
+
+	return objects, nil
+}
+
+// Presign returns a temporary download URL signed with the store credentials
+func (s *S3Store) Presign(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	u, err := s.Client.PresignedGetObject(ctx, s.Bucket, key, expiry, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to presign %s: %w", key, err)
+	}
+	return u.String(), nil
+}
+
+// URL returns the public URL of the object
+func (s *S3Store) URL(key string) string {
+	return s.publicURL + "/" + key
+}
@@ -2,49 +2,86 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
-
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
+	"time"
 )
 
-type SpacesUploader struct {
-	Client *minio.Client
-	Bucket string
+// DefaultBucket is the bucket the diários are stored in when STORAGE_BUCKET is not set
+const DefaultBucket = "radar-oficial-diarios-piaui"
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
 }
 
-func NewSpacesUploader(bucket string) (*SpacesUploader, error) {
-	endpoint := os.Getenv("DO_SPACES_ENDPOINT")
-	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
-	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
-
-	minioClient, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
-		Secure: true,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to init spaces client: %w", err)
-	}
+// BlobStore stores the original PDFs of the diários
+type BlobStore interface {
+	// Put stores content with a known size under key, replacing any existing object
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+
+	// Get opens the object stored under key. The caller must close the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Stat returns the metadata of the object stored under key
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
 
-	return &SpacesUploader{
-		Client: minioClient,
-		Bucket: bucket,
-	}, nil
+	// Delete removes the object stored under key. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+
+	// List returns the objects whose key starts with prefix
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+
+	// Presign returns a temporary URL to download the object
+	Presign(ctx context.Context, key string, expiry time.Duration) (string, error)
+
+	// URL returns the permanent public URL of the object
+	URL(key string) string
 }
 
-// UploadFile uploads content from a reader with known size
-func (s *SpacesUploader) UploadFile(ctx context.Context, objectKey string, content io.Reader, size int64, contentType string) error {
-	_, err := s.Client.PutObject(ctx, s.Bucket, objectKey, content, size, minio.PutObjectOptions{
-		ContentType:  contentType,
-		UserMetadata: map[string]string{"x-amz-acl": "public-read"},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to upload to Spaces: %w", err)
+// NewBlobStoreFromEnv returns the store selected by STORAGE_BACKEND, which can be
+// "s3" (default, any S3-compatible endpoint such as DigitalOcean Spaces or MinIO)
+// or "local" (files under STORAGE_LOCAL_DIR, for development and tests)
+func NewBlobStoreFromEnv() (BlobStore, error) {
+	bucket := os.Getenv("STORAGE_BUCKET")
+	if bucket == "" {
+		bucket = DefaultBucket
 	}
 
-	log.Printf("✅ Uploaded to Spaces: %s/%s", s.Bucket, objectKey)
-	return nil
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "s3":
+		// DO_SPACES_ENDPOINT is kept for existing deployments
+		endpoint := os.Getenv("STORAGE_ENDPOINT")
+		if endpoint == "" {
+			endpoint = os.Getenv("DO_SPACES_ENDPOINT")
+		}
+
+		return NewS3Store(S3Config{
+			Endpoint:     endpoint,
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			Region:       os.Getenv("STORAGE_REGION"),
+			Bucket:       bucket,
+			Secure:       os.Getenv("STORAGE_INSECURE") != "true",
+			PublicURL:    os.Getenv("STORAGE_PUBLIC_URL"),
+			CreateBucket: os.Getenv("STORAGE_CREATE_BUCKET") == "true",
+		})
+	case "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "./data/storage"
+		}
+
+		return NewLocalStore(dir, bucket, os.Getenv("STORAGE_PUBLIC_URL"))
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q, expected s3 or local", backend)
+	}
 }
@@ -0,0 +1,314 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"radaroficial.app/internal/embedding"
+	"radaroficial.app/internal/model"
+	"radaroficial.app/internal/search"
+)
+
+// ErrSchemaMismatch is returned by EnsureSchema when the stored vectors have another
+// dimension than the embedder produces
+var ErrSchemaMismatch = errors.New("pgvector schema mismatch")
+
+// PgVectorStore stores the atos with their embeddings in Postgres, ranking them by
+// cosine similarity through an HNSW index. Keyword matches are ranked by
+// search.HybridService over the atos table.
+type PgVectorStore struct {
+	DB       *pgxpool.Pool
+	Embedder embedding.Embedder
+}
+
+// NewPgVectorStore creates a new PgVectorStore
+func NewPgVectorStore(db *pgxpool.Pool, embedder embedding.Embedder) *PgVectorStore {
+	return &PgVectorStore{DB: db, Embedder: embedder}
+}
+
+// EnsureSchema creates the pgvector extension and the ato_embeddings table. The table is
+// not created by the migrations, so deployments indexing in weaviate do not need the
+// extension installed.
+func (s *PgVectorStore) EnsureSchema(ctx context.Context) error {
+	if _, err := s.DB.Exec(ctx, `CREATE EXTENSION IF NOT EXISTS vector`); err != nil {
+		return fmt.Errorf("failed to create pgvector extension: %w", err)
+	}
+
+	table := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS ato_embeddings (
+			diario_id      INT NOT NULL REFERENCES diarios(id) ON DELETE CASCADE,
+			seq            INT NOT NULL,
+			ato_id         INT NOT NULL,
+			institution_id INT NOT NULL,
+			institution    TEXT NOT NULL,
+			entity         TEXT NOT NULL,
+			state          TEXT NOT NULL,
+			city           TEXT,
+			description    TEXT NOT NULL,
+			edition_number INT,
+			published_at   TIMESTAMPTZ,
+			source_url     TEXT NOT NULL,
+			act_type       TEXT NOT NULL,
+			title          TEXT NOT NULL,
+			content        TEXT NOT NULL,
+			page           INT NOT NULL,
+			page_end       INT NOT NULL,
+			embedding      vector(%d) NOT NULL,
+			embedding_model TEXT NOT NULL,
+			indexed_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (diario_id, seq)
+		)
+	`, s.Embedder.Dimensions())
+
+	if _, err := s.DB.Exec(ctx, table); err != nil {
+		return fmt.Errorf("failed to create ato_embeddings table: %w", err)
+	}
+
+	// The type modifier of a vector column is its dimension
+	var dimensions int
+	err := s.DB.QueryRow(ctx, `
+		SELECT atttypmod FROM pg_attribute
+		WHERE attrelid = 'ato_embeddings'::regclass AND attname = 'embedding'
+	`).Scan(&dimensions)
+	if err != nil {
+		return fmt.Errorf("failed to check ato_embeddings dimension: %w", err)
+	}
+
+	if dimensions != s.Embedder.Dimensions() {
+		return fmt.Errorf("%w: ato_embeddings stores %d dimensions, the embedder produces %d; drop the table and reindex the diarios",
+			ErrSchemaMismatch, dimensions, s.Embedder.Dimensions())
+	}
+
+	// Tables created before the model was recorded
+	if _, err := s.DB.Exec(ctx, `ALTER TABLE ato_embeddings ADD COLUMN IF NOT EXISTS embedding_model TEXT NOT NULL DEFAULT ''`); err != nil {
+		return fmt.Errorf("failed to add embedding_model to ato_embeddings: %w", err)
+	}
+
+	indexes := []string{
+		`CREATE INDEX IF NOT EXISTS idx_ato_embeddings_embedding ON ato_embeddings USING hnsw (embedding vector_cosine_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_ato_embeddings_published_at ON ato_embeddings (published_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_ato_embeddings_institution_id ON ato_embeddings (institution_id)`,
+		// Tables created when Search fused its own full-text ranking
+		`DROP INDEX IF EXISTS idx_ato_embeddings_tsv`,
+		`ALTER TABLE ato_embeddings DROP COLUMN IF EXISTS tsv`,
+	}
+	for _, index := range indexes {
+		if _, err := s.DB.Exec(ctx, index); err != nil {
+			return fmt.Errorf("failed to update ato_embeddings indexes: %w", err)
+		}
+	}
+
+	// Vectors of another model are not comparable with the queries, and are ignored by Search
+	var stale int
+	err = s.DB.QueryRow(ctx, `SELECT COUNT(*) FROM ato_embeddings WHERE embedding_model <> $1`, s.Embedder.Model()).Scan(&stale)
+	if err != nil {
+		return fmt.Errorf("failed to count stale embeddings: %w", err)
+	}
+	if stale > 0 {
+		log.Printf("⚠️ Warning: %d ato(s) were embedded by another model than %s, reindex their diarios", stale, s.Embedder.Model())
+	}
+
+	log.Printf("✅ pgvector table ato_embeddings is up to date")
+	return nil
+}
+
+// UpsertAtos embeds the atos of a diario and stores them, replacing those stored at the
+// same positions
+func (s *PgVectorStore) UpsertAtos(ctx context.Context, atos []model.Ato, diario *model.Diario, institution *model.Institution) error {
+	if len(atos) == 0 {
+		return nil
+	}
+
+	description := ""
+	if diario.Description != nil {
+		description = *diario.Description
+	}
+
+	texts := make([]string, len(atos))
+	for i, ato := range atos {
+		texts[i] = embedding.AtoText(diario, institution, ato)
+	}
+
+	embeddings, err := s.Embedder.Embed(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed atos: %w", err)
+	}
+
+	tx, err := s.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO ato_embeddings (
+			diario_id, seq, ato_id, institution_id, institution, entity, state, city,
+			description, edition_number, published_at, source_url, act_type, title, content,
+			page, page_end, embedding, embedding_model, indexed_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18::vector, $19, NOW())
+		ON CONFLICT (diario_id, seq) DO UPDATE SET
+			ato_id = EXCLUDED.ato_id,
+			institution_id = EXCLUDED.institution_id,
+			institution = EXCLUDED.institution,
+			entity = EXCLUDED.entity,
+			state = EXCLUDED.state,
+			city = EXCLUDED.city,
+			description = EXCLUDED.description,
+			edition_number = EXCLUDED.edition_number,
+			published_at = EXCLUDED.published_at,
+			source_url = EXCLUDED.source_url,
+			act_type = EXCLUDED.act_type,
+			title = EXCLUDED.title,
+			content = EXCLUDED.content,
+			page = EXCLUDED.page,
+			page_end = EXCLUDED.page_end,
+			embedding = EXCLUDED.embedding,
+			embedding_model = EXCLUDED.embedding_model,
+			indexed_at = NOW()
+	`
+
+	for i, ato := range atos {
+		var city *string
+		if c := search.CityOf(institution, ato); c != "" {
+			city = &c
+		}
+
+		_, err := tx.Exec(ctx, query,
+			diario.ID, ato.Seq, ato.ID, institution.ID, institution.Slug, institution.Name,
+			institution.State, city, description, diario.EditionNumber, diario.PublishedAt,
+			diario.SourceURL, ato.ActType, ato.Title, ato.Content, ato.PageStart, ato.PageEnd,
+			vectorLiteral(embeddings[i]), s.Embedder.Model(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to store ato %d: %w", ato.Seq, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteStale removes the atos stored for a diario at positions other than those of atos
+func (s *PgVectorStore) DeleteStale(ctx context.Context, diario *model.Diario, atos []model.Ato) (int64, error) {
+	seqs := make([]int, len(atos))
+	for i, ato := range atos {
+		seqs[i] = ato.Seq
+	}
+
+	tag, err := s.DB.Exec(ctx, `DELETE FROM ato_embeddings WHERE diario_id = $1 AND NOT seq = ANY($2)`, diario.ID, seqs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete stale atos of diario %d: %w", diario.ID, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// DeleteDiario removes the atos stored for a diario
+func (s *PgVectorStore) DeleteDiario(ctx context.Context, diarioID int) (int64, error) {
+	tag, err := s.DB.Exec(ctx, `DELETE FROM ato_embeddings WHERE diario_id = $1`, diarioID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete atos of diario %d: %w", diarioID, err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// Search returns the atos nearest to query by cosine distance. It ranks by meaning only,
+// as the semantic half of search.HybridService.
+func (s *PgVectorStore) Search(ctx context.Context, query string, filter search.Filter, limit int) ([]search.Hit, error) {
+	embeddings, err := s.Embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	args := []any{vectorLiteral(embeddings[0]), limit, s.Embedder.Model()}
+	where := "embedding_model = $3 AND " + whereOf(filter, &args)
+
+	sql := fmt.Sprintf(`
+		SELECT
+			ato_id, diario_id, institution_id, entity, state, COALESCE(city, ''),
+			description, edition_number, published_at, source_url, act_type,
+			title, content, page, page_end, (1 - (embedding <=> $1::vector))::float8
+		FROM ato_embeddings
+		WHERE %s
+		ORDER BY embedding <=> $1::vector
+		LIMIT $2
+	`, where)
+
+	rows, err := s.DB.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search atos: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []search.Hit{}
+	for rows.Next() {
+		var h search.Hit
+		if err := rows.Scan(
+			&h.AtoID, &h.DiarioID, &h.InstitutionID, &h.Institution, &h.State, &h.City,
+			&h.Description, &h.EditionNumber, &h.PublishedAt, &h.SourceURL, &h.ActType,
+			&h.Title, &h.Content, &h.Page, &h.PageEnd, &h.Score,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan search hit: %w", err)
+		}
+		hits = append(hits, h)
+	}
+
+	return hits, rows.Err()
+}
+
+// whereOf translates a filter to a SQL condition, appending its values to args
+func whereOf(filter search.Filter, args *[]any) string {
+	conditions := []string{"TRUE"}
+
+	arg := func(v any) string {
+		*args = append(*args, v)
+		return "$" + strconv.Itoa(len(*args))
+	}
+
+	if filter.From != nil {
+		conditions = append(conditions, "published_at >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "published_at < "+arg(*filter.To))
+	}
+	if len(filter.InstitutionIDs) > 0 {
+		conditions = append(conditions, "institution_id = ANY("+arg(filter.InstitutionIDs)+")")
+	}
+	if len(filter.InstitutionSlugs) > 0 {
+		conditions = append(conditions, "institution = ANY("+arg(filter.InstitutionSlugs)+")")
+	}
+	if filter.State != "" {
+		conditions = append(conditions, "state = "+arg(filter.State))
+	}
+	if len(filter.ActTypes) > 0 {
+		conditions = append(conditions, "act_type = ANY("+arg(filter.ActTypes)+")")
+	}
+	if len(filter.Cities) > 0 {
+		conditions = append(conditions, "city = ANY("+arg(filter.Cities)+")")
+	}
+	if filter.DiarioID != 0 {
+		conditions = append(conditions, "diario_id = "+arg(filter.DiarioID))
+	}
+
+	return strings.Join(conditions, " AND ")
+}
+
+// vectorLiteral formats an embedding as a pgvector literal, e.g. "[0.1,0.2]"
+func vectorLiteral(embedding []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, v := range embedding {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(v), 'f', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
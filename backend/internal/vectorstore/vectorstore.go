@@ -0,0 +1,62 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"radaroficial.app/internal/embedding"
+	"radaroficial.app/internal/model"
+	"radaroficial.app/internal/search"
+	"radaroficial.app/internal/weaviate"
+)
+
+// VectorStore indexes the atos of diarios for hybrid (keyword and vector) retrieval.
+// Atos are keyed by diario and position, so indexing a diario again replaces its atos.
+type VectorStore interface {
+	// EnsureSchema creates or migrates the collection, failing when it cannot be migrated
+	EnsureSchema(ctx context.Context) error
+	// UpsertAtos indexes the atos of a diario, replacing those indexed at the same positions
+	UpsertAtos(ctx context.Context, atos []model.Ato, diario *model.Diario, institution *model.Institution) error
+	// DeleteStale removes the atos indexed for a diario other than atos, which were upserted
+	DeleteStale(ctx context.Context, diario *model.Diario, atos []model.Ato) (int64, error)
+	// DeleteDiario removes every ato indexed for a diario and returns how many were removed
+	DeleteDiario(ctx context.Context, diarioID int) (int64, error)
+	// Search returns the limit atos best matching query among those matching filter
+	Search(ctx context.Context, query string, filter search.Filter, limit int) ([]search.Hit, error)
+}
+
+var (
+	_ VectorStore = (*weaviate.Store)(nil)
+	_ VectorStore = (*PgVectorStore)(nil)
+)
+
+// NewVectorStoreFromEnv creates the vector store selected by VECTOR_STORE: weaviate
+// (default) or pgvector, storing vectors in db next to the diarios. Vectors are computed
+// by the embedder selected by EMBEDDER, except in weaviate with a vectorizer module,
+// i.e. unless WEAVIATE_VECTORIZER is none.
+func NewVectorStoreFromEnv(db *pgxpool.Pool) (VectorStore, error) {
+	switch backend := os.Getenv("VECTOR_STORE"); backend {
+	case "", "weaviate":
+		if os.Getenv("WEAVIATE_VECTORIZER") != "none" {
+			return weaviate.NewStore(nil)
+		}
+
+		embedder, err := embedding.NewEmbedderFromEnv()
+		if err != nil {
+			return nil, err
+		}
+
+		return weaviate.NewStore(embedder)
+	case "pgvector":
+		embedder, err := embedding.NewEmbedderFromEnv()
+		if err != nil {
+			return nil, err
+		}
+
+		return NewPgVectorStore(db, embedder), nil
+	default:
+		return nil, fmt.Errorf("unknown VECTOR_STORE %q, expected weaviate or pgvector", backend)
+	}
+}
@@ -0,0 +1,198 @@
+package weaviate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/weaviate/weaviate-go-client/v5/weaviate"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+// ClassName is the collection holding one object per ato. Weaviate capitalizes class
+// names, so objects written to "diarios" by older code live in the same class.
+const ClassName = "Diarios"
+
+// ErrSchemaMismatch is returned by EnsureSchema when the live class cannot be migrated
+// to the expected schema without recreating it
+var ErrSchemaMismatch = errors.New("weaviate schema mismatch")
+
+// text properties holding codes rather than prose are matched as a whole and left out
+// of the vectors
+var skipVectorization = map[string]any{"text2vec-openai": map[string]any{"skip": true}}
+
+// properties declares the schema of the collection, every property written by UpsertAtos
+// must be listed here
+var properties = []*models.Property{
+	{Name: "content", DataType: []string{"text"}, Description: "Text of the ato"},
+	{Name: "description", DataType: []string{"text"}, Description: "Description of the diario"},
+	{Name: "entity", DataType: []string{"text"}, Description: "Name of the institution publishing the diario"},
+	{Name: "title", DataType: []string{"text"}, Description: "Heading of the ato"},
+	{Name: "orgao", DataType: []string{"text"}, Description: "Issuing body"},
+	{Name: "actType", DataType: []string{"text"}, Tokenization: "field", ModuleConfig: skipVectorization, Description: "Kind of ato, lowercase, e.g. portaria"},
+	{Name: "number", DataType: []string{"text"}, Tokenization: "field", ModuleConfig: skipVectorization, Description: "Number of the ato"},
+	{Name: "year", DataType: []string{"int"}, Description: "Year of the ato"},
+	{Name: "page", DataType: []string{"int"}, Description: "First page of the ato"},
+	{Name: "pageEnd", DataType: []string{"int"}, Description: "Last page of the ato"},
+	{Name: "diarioId", DataType: []string{"int"}, Description: "ID of the diario in Postgres"},
+	{Name: "atoId", DataType: []string{"int"}, Description: "ID of the ato in Postgres"},
+	{Name: "editionNumber", DataType: []string{"int"}, Description: "Edition number of the diario"},
+	{Name: "institutionId", DataType: []string{"int"}, Description: "ID of the institution in Postgres"},
+	{Name: "institution", DataType: []string{"text"}, Tokenization: "field", ModuleConfig: skipVectorization, Description: "Slug of the institution"},
+	{Name: "state", DataType: []string{"text"}, Tokenization: "field", ModuleConfig: skipVectorization, Description: "State of the institution, e.g. PI"},
+	{Name: "city", DataType: []string{"text"}, Tokenization: "field", ModuleConfig: skipVectorization, Description: "Municipality of the ato, normalized by search.NormalizeCity"},
+	{Name: "publishedAt", DataType: []string{"date"}, Description: "Publication date of the diario"},
+	{Name: "embeddingModel", DataType: []string{"text"}, Tokenization: "field", ModuleConfig: skipVectorization, Description: "Model that produced the vector of the ato"},
+	{Name: "sourceUrl", DataType: []string{"text"}, Tokenization: "field", ModuleConfig: skipVectorization, Description: "URL of the stored PDF"},
+	{Name: "ocr", DataType: []string{"boolean"}, Description: "Whether the text came from OCR"},
+	{Name: "ocrConfidence", DataType: []string{"number"}, Description: "Lowest OCR confidence of the pages of the ato"},
+}
+
+// vectorizer returns the vectorizer module of the collection, text2vec-openai unless
+// WEAVIATE_VECTORIZER is set, and its module config. It is "none" when vectors are
+// computed by the embedder of the store.
+func (s *Store) vectorizer() (string, map[string]any) {
+	if s.embedder != nil {
+		return "none", nil
+	}
+
+	name := os.Getenv("WEAVIATE_VECTORIZER")
+	if name == "" {
+		name = "text2vec-openai"
+	}
+
+	if name != "text2vec-openai" {
+		return name, nil
+	}
+
+	return name, map[string]any{
+		name: map[string]any{"model": vectorizerModel(), "vectorizeClassName": false},
+	}
+}
+
+// vectorizerModel returns the model of the text2vec-openai module, from
+// WEAVIATE_VECTORIZER_MODEL
+func vectorizerModel() string {
+	if model := os.Getenv("WEAVIATE_VECTORIZER_MODEL"); model != "" {
+		return model
+	}
+	return "text-embedding-3-large"
+}
+
+// expectedClass returns the class declared by this package
+func (s *Store) expectedClass() *models.Class {
+	name, moduleConfig := s.vectorizer()
+
+	props := make([]*models.Property, len(properties))
+	for i, p := range properties {
+		prop := *p
+		if name != "text2vec-openai" {
+			prop.ModuleConfig = nil
+		}
+		props[i] = &prop
+	}
+
+	return &models.Class{
+		Class:        ClassName,
+		Description:  "Atos published in diários oficiais",
+		Vectorizer:   name,
+		ModuleConfig: moduleConfig,
+		Properties:   props,
+	}
+}
+
+// EnsureSchema creates the collection when missing and adds properties missing from an
+// existing one. It returns ErrSchemaMismatch when the live class uses another vectorizer
+// or a property has another data type, which Weaviate cannot change in place: delete the
+// class (see scripts/weavier/delete_collection.http) and reindex the diarios.
+func (s *Store) EnsureSchema(ctx context.Context) error {
+	if os.Getenv("WEAVIATE_HOST") == "" {
+		log.Printf("⚠️ Warning: WEAVIATE_HOST is not set, skipping weaviate schema check")
+		return nil
+	}
+
+	client := s.client
+	expected := s.expectedClass()
+
+	exists, err := client.Schema().ClassExistenceChecker().WithClassName(ClassName).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check weaviate class %s: %w", ClassName, err)
+	}
+
+	if !exists {
+		if err := client.Schema().ClassCreator().WithClass(expected).Do(ctx); err != nil {
+			return fmt.Errorf("failed to create weaviate class %s: %w", ClassName, err)
+		}
+
+		log.Printf("🆕 Created weaviate class %s", ClassName)
+		return nil
+	}
+
+	live, err := client.Schema().ClassGetter().WithClassName(ClassName).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get weaviate class %s: %w", ClassName, err)
+	}
+
+	missing, problems := diffClass(expected, live)
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: class %s %s", ErrSchemaMismatch, ClassName, strings.Join(problems, "; "))
+	}
+
+	for _, prop := range missing {
+		if err := addProperty(ctx, client, prop); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("✅ Weaviate class %s is up to date", ClassName)
+	return nil
+}
+
+// diffClass compares the live class with the expected one, returning the properties
+// missing from it and the differences that cannot be migrated
+func diffClass(expected, live *models.Class) (missing []*models.Property, problems []string) {
+	if live.Vectorizer != expected.Vectorizer {
+		problems = append(problems, fmt.Sprintf("uses vectorizer %q, expected %q", live.Vectorizer, expected.Vectorizer))
+	}
+
+	liveProps := make(map[string]*models.Property, len(live.Properties))
+	for _, p := range live.Properties {
+		liveProps[p.Name] = p
+	}
+
+	for _, want := range expected.Properties {
+		got, ok := liveProps[want.Name]
+		if !ok {
+			missing = append(missing, want)
+			continue
+		}
+
+		if !slices.Equal(got.DataType, want.DataType) {
+			problems = append(problems, fmt.Sprintf("property %s is %v, expected %v", want.Name, got.DataType, want.DataType))
+			continue
+		}
+
+		if want.Tokenization != "" && got.Tokenization != want.Tokenization {
+			problems = append(problems, fmt.Sprintf("property %s uses %q tokenization, expected %q", want.Name, got.Tokenization, want.Tokenization))
+		}
+	}
+
+	return missing, problems
+}
+
+func addProperty(ctx context.Context, client *weaviate.Client, prop *models.Property) error {
+	err := client.Schema().PropertyCreator().
+		WithClassName(ClassName).
+		WithProperty(prop).
+		Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to add property %s to weaviate class %s: %w", prop.Name, ClassName, err)
+	}
+
+	log.Printf("🆕 Added property %s to weaviate class %s", prop.Name, ClassName)
+	return nil
+}
@@ -0,0 +1,188 @@
+package weaviate
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/filters"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/graphql"
+	"github.com/weaviate/weaviate/entities/models"
+	"radaroficial.app/internal/search"
+)
+
+// hitFields are the properties read back for every search hit
+var hitFields = []graphql.Field{
+	{Name: "atoId"}, {Name: "diarioId"}, {Name: "institutionId"}, {Name: "entity"},
+	{Name: "state"}, {Name: "city"}, {Name: "description"}, {Name: "editionNumber"},
+	{Name: "publishedAt"}, {Name: "sourceUrl"}, {Name: "actType"}, {Name: "title"},
+	{Name: "content"}, {Name: "page"}, {Name: "pageEnd"},
+	{Name: "_additional", Fields: []graphql.Field{{Name: "score"}}},
+}
+
+// Search runs a hybrid (keyword and vector) search for query over the atos matching
+// filter and returns the best limit hits
+func (s *Store) Search(ctx context.Context, query string, filter search.Filter, limit int) ([]search.Hit, error) {
+
+	hybrid := s.client.GraphQL().HybridArgumentBuilder().WithQuery(query)
+
+	// Without a vectorizer module, the query is vectorized by the same embedder as the atos
+	if s.embedder != nil {
+		vectors, err := s.embedder.Embed(ctx, []string{query})
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed query: %w", err)
+		}
+		hybrid = hybrid.WithVector(models.C11yVector(vectors[0]))
+	}
+
+	get := s.client.GraphQL().Get().
+		WithClassName(ClassName).
+		WithHybrid(hybrid).
+		WithFields(hitFields...).
+		WithLimit(limit)
+
+	if where := whereOf(filter); where != nil {
+		get = get.WithWhere(where)
+	}
+
+	res, err := get.Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search weaviate: %v", err)
+	}
+
+	if len(res.Errors) > 0 {
+		return nil, fmt.Errorf("failed to search weaviate: %s", res.Errors[0].Message)
+	}
+
+	data, _ := res.Data["Get"].(map[string]any)
+	objects, _ := data[ClassName].([]any)
+
+	hits := make([]search.Hit, 0, len(objects))
+	for _, o := range objects {
+		if props, ok := o.(map[string]any); ok {
+			hits = append(hits, hitOf(props))
+		}
+	}
+
+	return hits, nil
+}
+
+// whereOf translates a filter to a weaviate where clause, nil for the zero filter
+func whereOf(filter search.Filter) *filters.WhereBuilder {
+	var operands []*filters.WhereBuilder
+
+	if filter.From != nil {
+		operands = append(operands, filters.Where().
+			WithPath([]string{"publishedAt"}).
+			WithOperator(filters.GreaterThanEqual).
+			WithValueDate(*filter.From))
+	}
+
+	if filter.To != nil {
+		operands = append(operands, filters.Where().
+			WithPath([]string{"publishedAt"}).
+			WithOperator(filters.LessThan).
+			WithValueDate(*filter.To))
+	}
+
+	if len(filter.InstitutionIDs) > 0 {
+		ids := make([]int64, len(filter.InstitutionIDs))
+		for i, id := range filter.InstitutionIDs {
+			ids[i] = int64(id)
+		}
+		operands = append(operands, filters.Where().
+			WithPath([]string{"institutionId"}).
+			WithOperator(filters.ContainsAny).
+			WithValueInt(ids...))
+	}
+
+	if len(filter.InstitutionSlugs) > 0 {
+		operands = append(operands, filters.Where().
+			WithPath([]string{"institution"}).
+			WithOperator(filters.ContainsAny).
+			WithValueText(filter.InstitutionSlugs...))
+	}
+
+	if len(filter.ActTypes) > 0 {
+		operands = append(operands, filters.Where().
+			WithPath([]string{"actType"}).
+			WithOperator(filters.ContainsAny).
+			WithValueText(filter.ActTypes...))
+	}
+
+	if filter.State != "" {
+		operands = append(operands, filters.Where().
+			WithPath([]string{"state"}).
+			WithOperator(filters.Equal).
+			WithValueText(filter.State))
+	}
+
+	if len(filter.Cities) > 0 {
+		operands = append(operands, filters.Where().
+			WithPath([]string{"city"}).
+			WithOperator(filters.ContainsAny).
+			WithValueText(filter.Cities...))
+	}
+
+	if filter.DiarioID != 0 {
+		operands = append(operands, filters.Where().
+			WithPath([]string{"diarioId"}).
+			WithOperator(filters.Equal).
+			WithValueInt(int64(filter.DiarioID)))
+	}
+
+	switch len(operands) {
+	case 0:
+		return nil
+	case 1:
+		return operands[0]
+	default:
+		return filters.Where().WithOperator(filters.And).WithOperands(operands)
+	}
+}
+
+// hitOf reads a hit from the properties of a GraphQL result, where numbers are float64
+func hitOf(props map[string]any) search.Hit {
+	hit := search.Hit{
+		AtoID:         intOf(props["atoId"]),
+		DiarioID:      intOf(props["diarioId"]),
+		InstitutionID: intOf(props["institutionId"]),
+		Institution:   stringOf(props["entity"]),
+		State:         stringOf(props["state"]),
+		City:          stringOf(props["city"]),
+		Description:   stringOf(props["description"]),
+		SourceURL:     stringOf(props["sourceUrl"]),
+		ActType:       stringOf(props["actType"]),
+		Title:         stringOf(props["title"]),
+		Content:       stringOf(props["content"]),
+		Page:          intOf(props["page"]),
+		PageEnd:       intOf(props["pageEnd"]),
+	}
+
+	if _, ok := props["editionNumber"].(float64); ok {
+		number := intOf(props["editionNumber"])
+		hit.EditionNumber = &number
+	}
+
+	if publishedAt, err := time.Parse(time.RFC3339, stringOf(props["publishedAt"])); err == nil {
+		hit.PublishedAt = &publishedAt
+	}
+
+	if additional, ok := props["_additional"].(map[string]any); ok {
+		// Hybrid scores are returned as strings
+		hit.Score, _ = strconv.ParseFloat(stringOf(additional["score"]), 64)
+	}
+
+	return hit
+}
+
+func intOf(v any) int {
+	f, _ := v.(float64)
+	return int(f)
+}
+
+func stringOf(v any) string {
+	s, _ := v.(string)
+	return s
+}
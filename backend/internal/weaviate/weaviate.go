@@ -4,16 +4,57 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
-	"regexp"
 	"strconv"
+	"time"
 
+	"github.com/go-openapi/strfmt"
+	"github.com/google/uuid"
 	"github.com/weaviate/weaviate-go-client/v5/weaviate"
+	"github.com/weaviate/weaviate-go-client/v5/weaviate/filters"
 	"github.com/weaviate/weaviate/entities/models"
+	"radaroficial.app/internal/embedding"
+	"radaroficial.app/internal/model"
+	"radaroficial.app/internal/search"
 )
 
-func UploadDir(dir, description, entity string) error {
+// objectNamespace scopes the UUIDs derived by ObjectID, it must never change or
+// reindexing would create new objects next to the existing ones
+var objectNamespace = uuid.MustParse("6f1c2a4e-9b7d-4f35-8a0e-3d52c1b7e9a4")
 
+// ObjectID derives the UUID of the object indexing the act at position seq of a diario,
+// so indexing the same diario again overwrites its objects instead of duplicating them
+func ObjectID(institutionID, diarioID, seq int) strfmt.UUID {
+	name := strconv.Itoa(institutionID) + "/" + strconv.Itoa(diarioID) + "/" + strconv.Itoa(seq)
+	return strfmt.UUID(uuid.NewSHA1(objectNamespace, []byte(name)).String())
+}
+
+// Store is the weaviate implementation of a vector store. Objects are vectorized by the
+// module configured on the class, see EnsureSchema, or by the embedder when set.
+type Store struct {
+	client   *weaviate.Client
+	embedder embedding.Embedder
+}
+
+// NewStore creates a Store for the weaviate instance at WEAVIATE_HOST. With a nil
+// embedder, vectors are computed server-side by the vectorizer module of the class.
+func NewStore(embedder embedding.Embedder) (*Store, error) {
+	client, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{client: client, embedder: embedder}, nil
+}
+
+// embeddingModel returns the model producing the vectors of the objects
+func (s *Store) embeddingModel() string {
+	if s.embedder != nil {
+		return s.embedder.Model()
+	}
+	return vectorizerModel()
+}
+
+func newClient() (*weaviate.Client, error) {
 	cfg := weaviate.Config{
 		Host:   os.Getenv("WEAVIATE_HOST"),
 		Scheme: "http",
@@ -21,63 +62,95 @@ func UploadDir(dir, description, entity string) error {
 
 	client, err := weaviate.NewClient(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create weaviate client: %v", err)
+		return nil, fmt.Errorf("failed to create weaviate client: %v", err)
 	}
 
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return fmt.Errorf("Error reading directory: %v", err)
+	return client, nil
+}
 
-	}
+// UpsertAtos indexes every act of a diario as its own object. Objects are written under
+// their ObjectID, replacing those written by a previous attempt.
+func (s *Store) UpsertAtos(ctx context.Context, atos []model.Ato, diario *model.Diario, institution *model.Institution) error {
 
 	var objects []*models.Object
-	// Regular expression to match the pattern "page_X.md"
-	// This will match files like page_1.md, page_01.md, page_001.md, etc.
-	pagePattern := regexp.MustCompile(`^page_(\d+)\.md$`)
-
-	for _, entry := range entries {
-		_, err := entry.Info()
-		if err != nil {
-			return fmt.Errorf("Error getting file info: %v", err)
-		}
 
-		// Check if the file matches our pattern
-		matches := pagePattern.FindStringSubmatch(entry.Name())
-		if matches == nil || len(matches) < 2 {
-			continue // Skip files that don't match the pattern
-		}
+	description := ""
+	if diario.Description != nil {
+		description = *diario.Description
+	}
 
-		// Extract the page number
-		pageNumStr := matches[1]
-		pageNum, err := strconv.Atoi(pageNumStr)
-		if err != nil {
-			return fmt.Errorf("Error parsing page number from %s: %v", entry.Name(), err)
+	var vectors [][]float32
+	if s.embedder != nil && len(atos) > 0 {
+		texts := make([]string, len(atos))
+		for i, ato := range atos {
+			texts[i] = embedding.AtoText(diario, institution, ato)
 		}
 
-		// Get full path to the file
-		filePath := filepath.Join(dir, entry.Name())
+		var err error
+		if vectors, err = s.embedder.Embed(ctx, texts); err != nil {
+			return fmt.Errorf("failed to embed atos: %w", err)
+		}
+	}
 
-		// Read file content as string
-		content, err := os.ReadFile(filePath)
-		if err != nil {
-			return fmt.Errorf("Error reading file %s: %v", entry.Name(), err)
+	for i, ato := range atos {
+		properties := map[string]any{
+			"description":    description,
+			"entity":         institution.Name,
+			"institution":    institution.Slug,
+			"institutionId":  institution.ID,
+			"state":          institution.State,
+			"sourceUrl":      diario.SourceURL,
+			"content":        ato.Content,
+			"page":           ato.PageStart,
+			"pageEnd":        ato.PageEnd,
+			"diarioId":       diario.ID,
+			"atoId":          ato.ID,
+			"actType":        ato.ActType,
+			"title":          ato.Title,
+			"embeddingModel": s.embeddingModel(),
+			// lets search results flag acts whose text came from OCR
+			"ocr": ato.OCR,
+		}
+		if city := search.CityOf(institution, ato); city != "" {
+			properties["city"] = city
+		}
+		if diario.EditionNumber != nil {
+			properties["editionNumber"] = *diario.EditionNumber
+		}
+		if diario.PublishedAt != nil {
+			properties["publishedAt"] = diario.PublishedAt.Format(time.RFC3339)
+		}
+		if ato.Orgao != nil {
+			properties["orgao"] = *ato.Orgao
+		}
+		if ato.Number != nil {
+			properties["number"] = *ato.Number
+		}
+		if ato.Year != nil {
+			properties["year"] = *ato.Year
+		}
+		if ato.OCRConfidence != nil {
+			properties["ocrConfidence"] = *ato.OCRConfidence
 		}
 
 		object := &models.Object{
-			Class: "diarios",
-			Properties: map[string]any{
-				"description": description,
-				"entity":      entity,
-				"content":     string(content),
-				"page":        pageNum,
-			},
+			ID:         ObjectID(diario.InstitutionID, diario.ID, ato.Seq),
+			Class:      ClassName,
+			Properties: properties,
+		}
+		if vectors != nil {
+			object.Vector = vectors[i]
 		}
 
 		objects = append(objects, object)
 	}
 
+	if len(objects) == 0 {
+		return nil
+	}
+
 	// batch write items
-	batchRes, err := client.Batch().ObjectsBatcher().WithObjects(objects...).Do(context.Background())
+	batchRes, err := s.client.Batch().ObjectsBatcher().WithObjects(objects...).Do(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to batch upload objects: %v", err)
 	}
@@ -90,3 +163,70 @@ func UploadDir(dir, description, entity string) error {
 
 	return nil
 }
+
+// DeleteStale removes the objects indexed for a diario other than those of atos, such as
+// atos of a previous version at positions the current one no longer has, and returns how
+// many were removed
+func (s *Store) DeleteStale(ctx context.Context, diario *model.Diario, atos []model.Ato) (int64, error) {
+
+	operands := []*filters.WhereBuilder{
+		filters.Where().
+			WithPath([]string{"diarioId"}).
+			WithOperator(filters.Equal).
+			WithValueInt(int64(diario.ID)),
+	}
+	for _, ato := range atos {
+		operands = append(operands, filters.Where().
+			WithPath([]string{"id"}).
+			WithOperator(filters.NotEqual).
+			WithValueText(ObjectID(diario.InstitutionID, diario.ID, ato.Seq).String()))
+	}
+
+	res, err := s.client.Batch().ObjectsBatchDeleter().
+		WithClassName(ClassName).
+		WithWhere(filters.Where().WithOperator(filters.And).WithOperands(operands)).
+		WithOutput("minimal").
+		Do(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete stale objects of diario %d: %v", diario.ID, err)
+	}
+
+	if res.Results == nil {
+		return 0, nil
+	}
+
+	if res.Results.Failed > 0 {
+		return res.Results.Successful, fmt.Errorf("failed to delete %d stale object(s) of diario %d", res.Results.Failed, diario.ID)
+	}
+
+	return res.Results.Successful, nil
+}
+
+// DeleteDiario removes every object indexed for a diario, including objects written
+// before IDs were deterministic, and returns how many were removed
+func (s *Store) DeleteDiario(ctx context.Context, diarioID int) (int64, error) {
+
+	where := filters.Where().
+		WithPath([]string{"diarioId"}).
+		WithOperator(filters.Equal).
+		WithValueInt(int64(diarioID))
+
+	res, err := s.client.Batch().ObjectsBatchDeleter().
+		WithClassName(ClassName).
+		WithWhere(where).
+		WithOutput("minimal").
+		Do(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete objects of diario %d: %v", diarioID, err)
+	}
+
+	if res.Results == nil {
+		return 0, nil
+	}
+
+	if res.Results.Failed > 0 {
+		return res.Results.Successful, fmt.Errorf("failed to delete %d object(s) of diario %d", res.Results.Failed, diarioID)
+	}
+
+	return res.Results.Successful, nil
+}
@@ -0,0 +1,74 @@
+package whatsapp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"radaroficial.app/internal/chat"
+)
+
+const (
+	// maxListedSources bounds the sources listed under an answer
+	maxListedSources = 5
+	// maxMessageLength is the longest text message accepted by WhatsApp
+	maxMessageLength = 4096
+)
+
+var (
+	markdownBoldRegexp    = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	markdownHeadingRegexp = regexp.MustCompile(`(?m)^#{1,6}\s+(.+)$`)
+)
+
+// FormatAnswer renders a chat answer as a WhatsApp message: Markdown bold and headings
+// become WhatsApp bold, and a short list of the cited sources follows, linking to their
+// page in the stored PDF. The answer is cut to fit a single message.
+func FormatAnswer(answer *chat.Answer) string {
+	text := markdownBoldRegexp.ReplaceAllString(answer.Text, "*$1*")
+	text = markdownHeadingRegexp.ReplaceAllString(text, "*$1*")
+
+	sources := formatSources(answer.Citations)
+
+	if room := maxMessageLength - len([]rune(sources)); len([]rune(text)) > room {
+		text = string([]rune(text)[:max(room-1, 0)]) + "…"
+	}
+
+	return text + sources
+}
+
+func formatSources(citations []chat.Citation) string {
+	if len(citations) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\n*Fontes:*")
+
+	for i, c := range citations {
+		if i == maxListedSources {
+			fmt.Fprintf(&b, "\n… e mais %d", len(citations)-maxListedSources)
+			break
+		}
+
+		parts := []string{}
+		if c.Institution != "" {
+			parts = append(parts, c.Institution)
+		}
+		if c.Description != "" {
+			parts = append(parts, c.Description)
+		}
+		if c.PublishedAt != nil {
+			parts = append(parts, c.PublishedAt.Format("02/01/2006"))
+		}
+		if c.Page > 0 {
+			parts = append(parts, fmt.Sprintf("p. %d", c.Page))
+		}
+
+		fmt.Fprintf(&b, "\n[%d] %s", c.Number, strings.Join(parts, ", "))
+		if c.URL != "" {
+			b.WriteString("\n" + c.URL)
+		}
+	}
+
+	return b.String()
+}
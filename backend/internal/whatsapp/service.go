@@ -9,6 +9,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -16,6 +17,7 @@ import (
 type WhatsAppService struct {
 	phoneNumberID    string
 	token            string
+	apiURL           string // Graph API base, WHATSAPP_API_URL points it to a fake server
 	userSessionSvc   *UserSessionService
 }
 
@@ -41,11 +43,17 @@ func NewWhatsAppService(db *pgxpool.Pool) (*WhatsAppService, error) {
 		return nil, fmt.Errorf("WHATSAPP_PHONE_NUMBER_ID environment variable not set")
 	}
 
+	apiURL := os.Getenv("WHATSAPP_API_URL")
+	if apiURL == "" {
+		apiURL = "https://graph.facebook.com/v22.0"
+	}
+
 	userSessionSvc := NewUserSessionService(db)
 
 	return &WhatsAppService{
 		phoneNumberID:  phoneNumberID,
 		token:          token,
+		apiURL:         strings.TrimSuffix(apiURL, "/"),
 		userSessionSvc: userSessionSvc,
 	}, nil
 }
@@ -70,7 +78,7 @@ func (s *WhatsAppService) SendWelcomeMessage(ctx context.Context, recipientID st
 }
 
 func (s *WhatsAppService) SendStateSelectionList(ctx context.Context, recipientID string) error {
-	url := fmt.Sprintf("https://graph.facebook.com/v22.0/%s/messages", s.phoneNumberID)
+	url := fmt.Sprintf("%s/%s/messages", s.apiURL, s.phoneNumberID)
 
 	// Define the interactive list payload
 	payload := map[string]interface{}{
@@ -167,7 +175,7 @@ func (s *WhatsAppService) GetUserState(ctx context.Context, phoneNumber string)
 }
 
 func (s *WhatsAppService) SendTextMessage(recipientID, message string) error {
-	url := fmt.Sprintf("https://graph.facebook.com/v22.0/%s/messages", s.phoneNumberID)
+	url := fmt.Sprintf("%s/%s/messages", s.apiURL, s.phoneNumberID)
 
 	// Construct the request payload
 	payload := map[string]interface{}{